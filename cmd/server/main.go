@@ -12,6 +12,8 @@ import (
 	"pr-reviewer/internal/repository"
 	"pr-reviewer/internal/service"
 	"pr-reviewer/internal/store"
+	"strconv"
+	"time"
 )
 
 func main() {
@@ -45,11 +47,38 @@ func main() {
 	userRepo := repository.NewUserRepositoryPG(pool)
 	teamRepo := repository.NewTeamRepositoryPG(pool)
 	prRepo := repository.NewPRRepositoryPG(pool) // ← из ранее созданного файла
+	reviewRepo := repository.NewReviewRepositoryPG(pool)
+	eventRepo := repository.NewEventRepositoryPG(pool)
+	absenceRepo := repository.NewAbsenceRepositoryPG(pool)
+	exclusionRepo := repository.NewExclusionRepositoryPG(pool)
+
+	// Reviewer selection strategies
+	seed := time.Now().UnixNano()
+	if v := os.Getenv("REVIEWER_RANDOM_SEED"); v != "" {
+		seed, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			logg.Sugar().Fatalf("invalid REVIEWER_RANDOM_SEED: %v", err)
+		}
+	}
+	pairingHalfLife := 30 * 24 * time.Hour
+	if v := os.Getenv("PAIRING_HALF_LIFE"); v != "" {
+		pairingHalfLife, err = time.ParseDuration(v)
+		if err != nil || pairingHalfLife <= 0 {
+			logg.Sugar().Fatalf("invalid PAIRING_HALF_LIFE: %q", v)
+		}
+	}
+	selectors := service.NewSelectorRegistry(
+		service.StrategyLeastLoaded,
+		service.NewRoundRobinSelector(teamRepo),
+		service.NewLeastLoadedSelector(),
+		service.NewRandomSelector(seed),
+		service.NewLeastPairedSelector(prRepo, pairingHalfLife),
+	)
 
 	// Services
-	userService := service.NewUserService(userRepo, teamRepo)
-	teamService := service.NewTeamService(teamRepo, userRepo)
-	prService := service.NewPRService(prRepo, userRepo, teamRepo)
+	prService := service.NewPRService(store, prRepo, userRepo, teamRepo, reviewRepo, eventRepo, absenceRepo, exclusionRepo, selectors, logg)
+	userService := service.NewUserService(store, userRepo, teamRepo, absenceRepo, exclusionRepo, prService, os.Getenv("CALENDAR_DIR"))
+	teamService := service.NewTeamService(store, teamRepo, userRepo, prRepo, selectors, prService)
 
 	// Handlers
 	userHandler := handlers.NewUsersHandler(userService, logg)
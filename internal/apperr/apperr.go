@@ -0,0 +1,95 @@
+// Package apperr defines the domain error taxonomy shared by repositories,
+// services and HTTP handlers.
+package apperr
+
+import (
+	"errors"
+	"net/http"
+)
+
+type Code string
+
+const (
+	CodeNotFound         Code = "NOT_FOUND"
+	CodeTeamExists       Code = "TEAM_EXISTS"
+	CodePRExists         Code = "PR_EXISTS"
+	CodePRMerged         Code = "PR_MERGED"
+	CodeNotAssigned      Code = "NOT_ASSIGNED"
+	CodeNoCandidate      Code = "NO_CANDIDATE"
+	CodeAlreadyExists    Code = "ALREADY_EXISTS"
+	CodeConflict         Code = "CONFLICT"
+	CodeMergeBlocked     Code = "MERGE_BLOCKED"
+	CodeInvalidState     Code = "INVALID_STATE"
+	CodeForbidden        Code = "FORBIDDEN"
+	CodeInvalidReference Code = "INVALID_REFERENCE"
+	CodeValidation       Code = "VALIDATION"
+	CodeBadRequest       Code = "BAD_REQUEST"
+	CodeInternal         Code = "INTERNAL"
+
+	// CodeConflictOfInterest rejects a reviewer excluded from the author's PRs.
+	CodeConflictOfInterest Code = "CONFLICT_OF_INTEREST"
+)
+
+// Error is a domain error with a machine readable code.
+type Error struct {
+	Code    Code
+	Message string
+	Err     error
+}
+
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Wrap attaches a code and message to a lower level error.
+func Wrap(err error, code Code, message string) *Error {
+	return &Error{Code: code, Message: message, Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// CodeOf returns the code of the first *Error in the chain, CodeInternal otherwise.
+func CodeOf(err error) Code {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return CodeInternal
+}
+
+// MessageOf returns the client facing message of err.
+func MessageOf(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Message
+	}
+	return "internal error"
+}
+
+// HTTPStatus maps a code to the response status.
+func HTTPStatus(code Code) int {
+	switch code {
+	case CodeNotFound:
+		return http.StatusNotFound
+	case CodeTeamExists, CodeBadRequest:
+		// TEAM_EXISTS is documented as 400 in openapi.yml
+		return http.StatusBadRequest
+	case CodePRExists, CodePRMerged, CodeNotAssigned, CodeNoCandidate, CodeAlreadyExists, CodeConflict, CodeMergeBlocked, CodeInvalidState, CodeConflictOfInterest:
+		return http.StatusConflict
+	case CodeForbidden:
+		return http.StatusForbidden
+	case CodeInvalidReference, CodeValidation:
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
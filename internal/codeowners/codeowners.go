@@ -0,0 +1,153 @@
+// Package codeowners reads CODEOWNERS files: path patterns in gitignore
+// syntax followed by the users (@name) and teams (@org/team) owning them.
+package codeowners
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Owner is a user or a team named by a rule. Handle is the owner as
+// written in the file, Name the user or team name without the prefix.
+type Owner struct {
+	Handle string
+	Name   string
+	Team   bool
+}
+
+// Rule is one line of the file. A rule without owners leaves the matching
+// paths unowned.
+type Rule struct {
+	Pattern string
+	Owners  []Owner
+	Line    int
+	re      *regexp.Regexp
+}
+
+// Ruleset is a parsed file. Later rules take precedence over earlier ones.
+type Ruleset []Rule
+
+type ParseError struct {
+	Line int
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("codeowners: line %d: %s", e.Line, e.Msg)
+}
+
+// Parse reads all rules of the file, skipping blank lines and comments.
+func Parse(r io.Reader) (Ruleset, error) {
+	sc := bufio.NewScanner(r)
+	var rules Ruleset
+	for n := 1; sc.Scan(); n++ {
+		fields := strings.Fields(sc.Text())
+		for i, f := range fields {
+			if strings.HasPrefix(f, "#") {
+				fields = fields[:i]
+				break
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		rule := Rule{Pattern: fields[0], Line: n}
+		re, err := compile(rule.Pattern)
+		if err != nil {
+			return nil, &ParseError{Line: n, Msg: err.Error()}
+		}
+		rule.re = re
+		for _, f := range fields[1:] {
+			o, err := parseOwner(f)
+			if err != nil {
+				return nil, &ParseError{Line: n, Msg: err.Error()}
+			}
+			rule.Owners = append(rule.Owners, o)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, sc.Err()
+}
+
+// Match returns the last rule matching the slash separated path, relative to
+// the repository root.
+func (rs Ruleset) Match(path string) (Rule, bool) {
+	path = strings.TrimPrefix(path, "/")
+	for i := len(rs) - 1; i >= 0; i-- {
+		if rs[i].re.MatchString(path) {
+			return rs[i], true
+		}
+	}
+	return Rule{}, false
+}
+
+// parseOwner accepts @user, @org/team and e-mail addresses; the latter are
+// kept as user names.
+func parseOwner(s string) (Owner, error) {
+	if name, ok := strings.CutPrefix(s, "@"); ok && name != "" {
+		if i := strings.LastIndex(name, "/"); i >= 0 {
+			if i == len(name)-1 {
+				return Owner{}, fmt.Errorf("invalid owner %q", s)
+			}
+			return Owner{Handle: s, Name: name[i+1:], Team: true}, nil
+		}
+		return Owner{Handle: s, Name: name}, nil
+	}
+	if strings.Contains(s, "@") {
+		return Owner{Handle: s, Name: s}, nil
+	}
+	return Owner{}, fmt.Errorf("invalid owner %q", s)
+}
+
+// compile turns a gitignore style pattern into a regular expression. A
+// pattern containing a slash other than a trailing one is relative to the
+// root, otherwise it matches at any depth. A pattern with a trailing slash
+// or a last segment without wildcards also matches everything below the
+// directory it names; docs/* matches the files directly in docs only.
+func compile(pattern string) (*regexp.Regexp, error) {
+	if strings.HasPrefix(pattern, "!") {
+		return nil, fmt.Errorf("negated pattern %q is not supported", pattern)
+	}
+	p, dirOnly := strings.CutSuffix(pattern, "/")
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+	if p == "" {
+		return nil, fmt.Errorf("invalid pattern %q", pattern)
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(p); i++ {
+		switch {
+		case strings.HasPrefix(p[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case p[i] == '*':
+			b.WriteString("[^/]*")
+		case p[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(p[i : i+1]))
+		}
+	}
+	last := p[strings.LastIndex(p, "/")+1:]
+	switch {
+	case dirOnly:
+		b.WriteString("/.*$")
+	case !strings.ContainsAny(last, "*?"):
+		b.WriteString("(?:/.*)?$")
+	default:
+		b.WriteString("$")
+	}
+	return regexp.Compile(b.String())
+}
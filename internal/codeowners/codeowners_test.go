@@ -0,0 +1,175 @@
+package codeowners
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		pattern string
+		match   []string
+		noMatch []string
+	}{
+		{
+			pattern: "*.js",
+			match:   []string{"app.js", "web/src/app.js"},
+			noMatch: []string{"app.jsx", "app.js.map"},
+		},
+		{
+			pattern: "docs/*",
+			match:   []string{"docs/a.md", "docs/.keep"},
+			noMatch: []string{"docs/a/b.md", "docs", "src/docs/a.md"},
+		},
+		{
+			pattern: "/docs/",
+			match:   []string{"docs/a.md", "docs/a/b.md"},
+			noMatch: []string{"docs", "src/docs/a.md"},
+		},
+		{
+			pattern: "docs/",
+			match:   []string{"docs/a.md", "docs/a/b.md", "src/docs/a.md"},
+			noMatch: []string{"docs", "mydocs/a.md"},
+		},
+		{
+			pattern: "apps",
+			match:   []string{"apps", "apps/web/main.go", "services/apps/api.go"},
+			noMatch: []string{"webapps/main.go", "apps.go"},
+		},
+		{
+			pattern: "/build/logs",
+			match:   []string{"build/logs", "build/logs/today.log"},
+			noMatch: []string{"src/build/logs/today.log"},
+		},
+		{
+			pattern: "**/logs",
+			match:   []string{"logs/a.log", "build/logs/a.log", "a/b/logs"},
+			noMatch: []string{"catalogs/a.log"},
+		},
+		{
+			pattern: "docs/**",
+			match:   []string{"docs/a.md", "docs/a/b/c.md"},
+			noMatch: []string{"src/docs/a.md"},
+		},
+		{
+			pattern: "src/**/test_*.go",
+			match:   []string{"src/test_a.go", "src/pkg/test_a.go", "src/a/b/test_a.go"},
+			noMatch: []string{"src/pkg/a_test.go", "lib/src/test_a.go"},
+		},
+		{
+			pattern: "file?.txt",
+			match:   []string{"file1.txt", "dir/fileA.txt"},
+			noMatch: []string{"file10.txt", "file/.txt"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			re, err := compile(tt.pattern)
+			if err != nil {
+				t.Fatalf("compile: %v", err)
+			}
+			for _, p := range tt.match {
+				if !re.MatchString(p) {
+					t.Errorf("%q does not match %q", tt.pattern, p)
+				}
+			}
+			for _, p := range tt.noMatch {
+				if re.MatchString(p) {
+					t.Errorf("%q matches %q", tt.pattern, p)
+				}
+			}
+		})
+	}
+}
+
+func TestCompileInvalid(t *testing.T) {
+	for _, pattern := range []string{"!docs/", "/", "//"} {
+		if _, err := compile(pattern); err == nil {
+			t.Errorf("compile(%q) succeeded, want an error", pattern)
+		}
+	}
+}
+
+const sample = `
+# default owners
+*                   @org/platform
+
+# frontend, except the generated bundle
+/web/               @alice @org/frontend   # trailing comment
+/web/dist/
+*.go                @bob
+/internal/billing/  @carol bob@example.com
+`
+
+func TestMatch(t *testing.T) {
+	rules, err := Parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(rules) != 5 {
+		t.Fatalf("got %d rules, want 5", len(rules))
+	}
+
+	tests := []struct {
+		path   string
+		line   int
+		owners []string
+	}{
+		{"README.md", 3, []string{"@org/platform"}},
+		{"web/index.html", 6, []string{"@alice", "@org/frontend"}},
+		{"/web/index.html", 6, []string{"@alice", "@org/frontend"}},
+		{"web/dist/app.js", 7, nil},
+		{"web/server.go", 8, []string{"@bob"}},
+		{"internal/billing/invoice.go", 9, []string{"@carol", "bob@example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rule, ok := rules.Match(tt.path)
+			if !ok {
+				t.Fatal("no rule matches")
+			}
+			if rule.Line != tt.line {
+				t.Errorf("matched line %d, want %d", rule.Line, tt.line)
+			}
+			var handles []string
+			for _, o := range rule.Owners {
+				handles = append(handles, o.Handle)
+			}
+			if strings.Join(handles, " ") != strings.Join(tt.owners, " ") {
+				t.Errorf("owners = %v, want %v", handles, tt.owners)
+			}
+		})
+	}
+}
+
+func TestParseOwners(t *testing.T) {
+	rules, err := Parse(strings.NewReader("docs/ @org/docs-team @dave\n"))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	want := []Owner{
+		{Handle: "@org/docs-team", Name: "docs-team", Team: true},
+		{Handle: "@dave", Name: "dave"},
+	}
+	got := rules[0].Owners
+	if len(got) != len(want) {
+		t.Fatalf("owners = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("owner %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	_, err := Parse(strings.NewReader("# owners\n\ndocs/ @org/\n"))
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("err = %v, want a ParseError", err)
+	}
+	if perr.Line != 3 {
+		t.Errorf("line = %d, want 3", perr.Line)
+	}
+}
@@ -19,89 +19,323 @@ func NewPRHandler(pr service.PRService, log *zap.Logger) *PRHandler {
 	return &PRHandler{pr: pr, log: log}
 }
 
+// CreatePR POST /pullRequest/create
 func (h *PRHandler) CreatePR(w http.ResponseWriter, r *http.Request) {
 	var in struct {
-		Name     string `json:"name"`
-		AuthorID string `json:"author_id"`
+		PullRequestID   string   `json:"pull_request_id"`
+		PullRequestName string   `json:"pull_request_name"`
+		AuthorID        string   `json:"author_id"`
+		TeamName        string   `json:"team_name"`
+		Strategy        string   `json:"strategy"`
+		ReviewerCount   *int     `json:"reviewer_count"`
+		Draft           bool     `json:"draft"`
+		ChangedPaths    []string `json:"changed_paths"`
+		RequiredSkills  []string `json:"required_skills"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
-		http.Error(w, "invalid body", http.StatusBadRequest)
+		badRequest(w, "invalid body")
+		return
+	}
+	if in.PullRequestID == "" || in.PullRequestName == "" || in.AuthorID == "" {
+		badRequest(w, "pull_request_id, pull_request_name and author_id required")
 		return
 	}
 
-	pr, reviewers, err := h.pr.CreatePR(r.Context(), in.Name, in.AuthorID)
+	pr, assignment, err := h.pr.CreatePR(r.Context(), service.CreatePRInput{
+		PullRequestID:  in.PullRequestID,
+		Name:           in.PullRequestName,
+		AuthorID:       in.AuthorID,
+		TeamName:       in.TeamName,
+		Strategy:       in.Strategy,
+		ReviewerCount:  in.ReviewerCount,
+		Draft:          in.Draft,
+		Paths:          in.ChangedPaths,
+		RequiredSkills: in.RequiredSkills,
+	})
 	if err != nil {
-		h.log.Error("CreatePR", zap.Error(err))
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		respondError(w, h.log, "CreatePR", err)
 		return
 	}
 
 	resp := struct {
-		PR        *models.PullRequest `json:"pr"`
-		Reviewers []models.User       `json:"reviewers"`
-	}{pr, reviewers}
+		PR         *models.PullRequest `json:"pr"`
+		Assignment *service.Assignment `json:"assignment,omitempty"`
+	}{pr, assignment}
 
-	json.NewEncoder(w).Encode(resp)
+	status := http.StatusCreated
+	if assignment == nil {
+		// retried create of an existing identical PR
+		status = http.StatusOK
+	}
+	writeJSON(w, status, resp)
 }
 
+// GetPR GET /pullRequest/{id}
 func (h *PRHandler) GetPR(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
-	pr, revs, err := h.pr.GetPR(r.Context(), id)
+	details, err := h.pr.GetPR(r.Context(), id)
 	if err != nil {
-		http.Error(w, "not found", http.StatusNotFound)
+		respondError(w, h.log, "GetPR", err)
 		return
 	}
 
-	resp := struct {
-		PR        *models.PullRequest `json:"pr"`
-		Reviewers []models.User       `json:"reviewers"`
-	}{pr, revs}
-
-	json.NewEncoder(w).Encode(resp)
+	writeJSON(w, http.StatusOK, details)
 }
 
+// MergePR POST /pullRequest/merge
 func (h *PRHandler) MergePR(w http.ResponseWriter, r *http.Request) {
-	id := chi.URLParam(r, "id")
-	pr, err := h.pr.MergePR(r.Context(), id)
+	var in struct {
+		PullRequestID string `json:"pull_request_id"`
+		// force bypasses the team merge policy; admins only
+		Force   bool   `json:"force"`
+		ActorID string `json:"actor_id"`
+		Reason  string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.PullRequestID == "" {
+		badRequest(w, "pull_request_id required")
+		return
+	}
+
+	pr, err := h.pr.MergePR(r.Context(), service.MergeInput{
+		PullRequestID: in.PullRequestID,
+		ActorID:       in.ActorID,
+		Force:         in.Force,
+		Reason:        in.Reason,
+	})
+	if err != nil {
+		respondError(w, h.log, "MergePR", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		PR *models.PullRequest `json:"pr"`
+	}{pr})
+}
+
+// AddReviewer POST /pullRequest/{id}/reviewers
+func (h *PRHandler) AddReviewer(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		UserID   string `json:"user_id"`
+		ActorID  string `json:"actor_id"`
+		Strategy string `json:"strategy"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		badRequest(w, "invalid body")
+		return
+	}
+
+	pr, assignment, err := h.pr.AddReviewer(r.Context(), service.AddReviewerInput{
+		PullRequestID: chi.URLParam(r, "id"),
+		UserID:        in.UserID,
+		ActorID:       in.ActorID,
+		Strategy:      in.Strategy,
+	})
+	if err != nil {
+		respondError(w, h.log, "AddReviewer", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		PR         *models.PullRequest `json:"pr"`
+		Assignment *service.Assignment `json:"assignment,omitempty"`
+	}{pr, assignment})
+}
+
+// RemoveReviewer DELETE /pullRequest/{id}/reviewers/{userID}?actor_id=
+func (h *PRHandler) RemoveReviewer(w http.ResponseWriter, r *http.Request) {
+	actorID := r.URL.Query().Get("actor_id")
+	pr, err := h.pr.RemoveReviewer(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "userID"), actorID)
+	if err != nil {
+		respondError(w, h.log, "RemoveReviewer", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		PR *models.PullRequest `json:"pr"`
+	}{pr})
+}
+
+// decodeTransition reads the body shared by the status transition endpoints.
+func decodeTransition(w http.ResponseWriter, r *http.Request) (service.TransitionInput, bool) {
+	var in struct {
+		PullRequestID string `json:"pull_request_id"`
+		ActorID       string `json:"actor_id"`
+		Strategy      string `json:"strategy"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.PullRequestID == "" {
+		badRequest(w, "pull_request_id required")
+		return service.TransitionInput{}, false
+	}
+	return service.TransitionInput{
+		PullRequestID: in.PullRequestID,
+		ActorID:       in.ActorID,
+		Strategy:      in.Strategy,
+	}, true
+}
+
+// MarkReady POST /pullRequest/ready
+func (h *PRHandler) MarkReady(w http.ResponseWriter, r *http.Request) {
+	in, ok := decodeTransition(w, r)
+	if !ok {
+		return
+	}
+
+	pr, assignment, err := h.pr.MarkReady(r.Context(), in)
+	if err != nil {
+		respondError(w, h.log, "MarkReady", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		PR         *models.PullRequest `json:"pr"`
+		Assignment *service.Assignment `json:"assignment"`
+	}{pr, assignment})
+}
+
+// ClosePR POST /pullRequest/close
+func (h *PRHandler) ClosePR(w http.ResponseWriter, r *http.Request) {
+	in, ok := decodeTransition(w, r)
+	if !ok {
+		return
+	}
+
+	pr, err := h.pr.ClosePR(r.Context(), in)
 	if err != nil {
-		h.log.Error("MergePR", zap.Error(err))
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		respondError(w, h.log, "ClosePR", err)
 		return
 	}
 
-	json.NewEncoder(w).Encode(pr)
+	writeJSON(w, http.StatusOK, struct {
+		PR *models.PullRequest `json:"pr"`
+	}{pr})
 }
 
+// ReopenPR POST /pullRequest/reopen
+func (h *PRHandler) ReopenPR(w http.ResponseWriter, r *http.Request) {
+	in, ok := decodeTransition(w, r)
+	if !ok {
+		return
+	}
+
+	pr, assignment, err := h.pr.ReopenPR(r.Context(), in)
+	if err != nil {
+		respondError(w, h.log, "ReopenPR", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		PR         *models.PullRequest `json:"pr"`
+		Assignment *service.Assignment `json:"assignment"`
+	}{pr, assignment})
+}
+
+// ReassignReviewer POST /pullRequest/reassign
 func (h *PRHandler) ReassignReviewer(w http.ResponseWriter, r *http.Request) {
-	id := chi.URLParam(r, "id")
 	var in struct {
-		OldReviewer string `json:"old_reviewer"`
+		PullRequestID string `json:"pull_request_id"`
+		OldUserID     string `json:"old_user_id"`
+		// old_reviewer_id is still accepted from older clients
+		OldReviewerID string `json:"old_reviewer_id"`
+		Strategy      string `json:"strategy"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		badRequest(w, "invalid body")
+		return
+	}
+	if in.OldUserID == "" {
+		in.OldUserID = in.OldReviewerID
+	}
+	if in.PullRequestID == "" || in.OldUserID == "" {
+		badRequest(w, "pull_request_id and old_user_id required")
+		return
 	}
-	json.NewDecoder(r.Body).Decode(&in)
 
-	newR, err := h.pr.ReassignReviewer(r.Context(), id, in.OldReviewer)
+	pr, assignment, err := h.pr.ReassignReviewer(r.Context(), in.PullRequestID, in.OldUserID, in.Strategy)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusConflict)
+		respondError(w, h.log, "ReassignReviewer", err)
 		return
 	}
 
-	json.NewEncoder(w).Encode(newR)
+	resp := struct {
+		PR         *models.PullRequest `json:"pr"`
+		ReplacedBy string              `json:"replaced_by"`
+		Assignment *service.Assignment `json:"assignment"`
+	}{pr, assignment.Reviewers[0].UserID, assignment}
+
+	writeJSON(w, http.StatusOK, resp)
 }
 
+// GetPRsByReviewer GET /users/getReview
 func (h *PRHandler) GetPRsByReviewer(w http.ResponseWriter, r *http.Request) {
 	reviewerID := r.URL.Query().Get("user_id")
 	if reviewerID == "" {
-		http.Error(w, "user_id required", http.StatusBadRequest)
+		badRequest(w, "user_id required")
 		return
 	}
 
 	prs, err := h.pr.ListByReviewer(r.Context(), reviewerID)
 	if err != nil {
-		h.log.Error("GetPRsByReviewer: service error", zap.Error(err))
-		http.Error(w, "internal error", http.StatusInternalServerError)
+		respondError(w, h.log, "GetPRsByReviewer", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		UserID       string                    `json:"user_id"`
+		PullRequests []models.ReviewAssignment `json:"pull_requests"`
+	}{reviewerID, prs})
+}
+
+// SubmitReview POST /pullRequest/review
+func (h *PRHandler) SubmitReview(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		PullRequestID string               `json:"pull_request_id"`
+		UserID        string               `json:"user_id"`
+		Verdict       models.ReviewVerdict `json:"verdict"`
+		Message       *string              `json:"message"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		badRequest(w, "invalid body")
+		return
+	}
+	if in.PullRequestID == "" || in.UserID == "" {
+		badRequest(w, "pull_request_id and user_id required")
+		return
+	}
+
+	review, err := h.pr.SubmitReview(r.Context(), in.PullRequestID, in.UserID, in.Verdict, in.Message)
+	if err != nil {
+		respondError(w, h.log, "SubmitReview", err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, struct {
+		Review *models.Review `json:"review"`
+	}{review})
+}
+
+// DeactivateUsers POST /users/deactivate
+func (h *PRHandler) DeactivateUsers(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		UserIDs  []string `json:"user_ids"`
+		TeamName string   `json:"team_name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		badRequest(w, "invalid body")
+		return
+	}
+	if len(in.UserIDs) == 0 && in.TeamName == "" {
+		badRequest(w, "user_ids or team_name required")
+		return
+	}
+
+	report, err := h.pr.DeactivateUsers(r.Context(), service.DeactivateInput{
+		UserIDs:  in.UserIDs,
+		TeamName: in.TeamName,
+	})
+	if err != nil {
+		respondError(w, h.log, "DeactivateUsers", err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(prs)
+	writeJSON(w, http.StatusOK, report)
 }
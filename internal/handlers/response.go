@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"pr-reviewer/internal/apperr"
+
+	"go.uber.org/zap"
+)
+
+type errorBody struct {
+	Code    apperr.Code `json:"code"`
+	Message string      `json:"message"`
+}
+
+type errorResponse struct {
+	Error errorBody `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code apperr.Code, message string) {
+	writeJSON(w, status, errorResponse{Error: errorBody{Code: code, Message: message}})
+}
+
+// badRequest answers 400 for malformed input that never reached a service.
+func badRequest(w http.ResponseWriter, message string) {
+	writeError(w, http.StatusBadRequest, apperr.CodeBadRequest, message)
+}
+
+// respondError maps a service error to its HTTP status and ErrorResponse body.
+// Unclassified errors are logged and hidden behind a 500.
+func respondError(w http.ResponseWriter, log *zap.Logger, op string, err error) {
+	code := apperr.CodeOf(err)
+	status := apperr.HTTPStatus(code)
+	if status >= http.StatusInternalServerError {
+		log.Error(op, zap.Error(err))
+	} else {
+		log.Info(op, zap.String("code", string(code)), zap.Error(err))
+	}
+	writeError(w, status, code, apperr.MessageOf(err))
+}
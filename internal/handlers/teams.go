@@ -2,9 +2,10 @@ package handlers
 
 import (
 	"encoding/json"
-	"errors"
 	"net/http"
+	"time"
 
+	"pr-reviewer/internal/models"
 	"pr-reviewer/internal/service"
 
 	"github.com/go-chi/chi/v5"
@@ -24,62 +25,54 @@ func NewTeamsHandler(ts service.TeamService, log *zap.Logger) *TeamsHandler {
 // AddTeam POST /team/add
 func (h *TeamsHandler) AddTeam(w http.ResponseWriter, r *http.Request) {
 	var in struct {
-		TeamName string `json:"team_name"`
-		Members  []struct {
-			UserID   *string `json:"user_id"`
-			Username string  `json:"username"`
-			IsActive bool    `json:"is_active"`
-		} `json:"members"`
+		TeamName string              `json:"team_name"`
+		Members  []models.TeamMember `json:"members"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
 		h.log.Error("AddTeam: decode failed", zap.Error(err))
-		http.Error(w, "invalid body", http.StatusBadRequest)
+		badRequest(w, "invalid body")
 		return
 	}
 
 	if in.TeamName == "" {
-		http.Error(w, "team_name required", http.StatusBadRequest)
+		badRequest(w, "team_name required")
 		return
 	}
-
-	team, err := h.teams.CreateTeam(r.Context(), in.TeamName, nil)
-	if err != nil {
-		h.log.Error("AddTeam: CreateTeam failed", zap.Error(err))
-		http.Error(w, "could not create team", http.StatusInternalServerError)
-		return
-	}
-
-	// обработка списка пользователей
 	for _, m := range in.Members {
 		if m.Username == "" {
-			http.Error(w, "username required", http.StatusBadRequest)
+			badRequest(w, "username required")
 			return
 		}
+	}
 
-		// ВАЖНО: твой сервис сам должен решать:
-		// - создать юзера, если нет id
-		// - обновить/активировать, если id есть
-		err := h.teams.AttachUser(
-			r.Context(),
-			team.TeamName,
-			m.UserID,
-			m.Username,
-			m.IsActive,
-		)
-		if err != nil {
-			h.log.Error("AddTeam: AttachUser failed", zap.Error(err))
-			http.Error(w, "failed to assign user", http.StatusInternalServerError)
-			return
-		}
+	team, err := h.teams.AddTeam(r.Context(), in.TeamName, in.Members)
+	if err != nil {
+		respondError(w, h.log, "AddTeam", err)
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	_ = json.NewEncoder(w).Encode(team)
+	writeJSON(w, http.StatusCreated, struct {
+		Team *models.TeamDetails `json:"team"`
+	}{team})
 }
 
-// CreateTeam POST /teams
+// GetTeamByQuery GET /team/get?team_name=
+func (h *TeamsHandler) GetTeamByQuery(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("team_name")
+	if name == "" {
+		badRequest(w, "team_name required")
+		return
+	}
+	team, err := h.teams.GetTeamDetails(r.Context(), name)
+	if err != nil {
+		respondError(w, h.log, "GetTeamByQuery", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, team)
+}
+
+// CreateTeam POST /team
 func (h *TeamsHandler) CreateTeam(w http.ResponseWriter, r *http.Request) {
 	var in struct {
 		TeamName    string  `json:"team_name"`
@@ -87,72 +80,235 @@ func (h *TeamsHandler) CreateTeam(w http.ResponseWriter, r *http.Request) {
 	}
 	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
 		h.log.Error("CreateTeam: decode", zap.Error(err))
-		http.Error(w, "invalid body", http.StatusBadRequest)
+		badRequest(w, "invalid body")
 		return
 	}
 	if in.TeamName == "" {
-		http.Error(w, "team_name required", http.StatusBadRequest)
+		badRequest(w, "team_name required")
 		return
 	}
 	t, err := h.teams.CreateTeam(r.Context(), in.TeamName, in.Description)
 	if err != nil {
-		h.log.Error("CreateTeam: service error", zap.Error(err))
-		// If duplicate key or validation error — could return 409. For now return 500.
-		http.Error(w, "could not create team", http.StatusInternalServerError)
+		respondError(w, h.log, "CreateTeam", err)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	_ = json.NewEncoder(w).Encode(t)
+	writeJSON(w, http.StatusCreated, t)
 }
 
-// GetTeam GET /teams/{team_name}
+// GetTeam GET /teams/{name}
 func (h *TeamsHandler) GetTeam(w http.ResponseWriter, r *http.Request) {
-	name := chi.URLParam(r, "team_name")
+	name := chi.URLParam(r, "name")
 	if name == "" {
-		http.Error(w, "team_name required", http.StatusBadRequest)
+		badRequest(w, "team_name required")
 		return
 	}
 	t, err := h.teams.GetTeam(r.Context(), name)
 	if err != nil {
-		h.log.Info("GetTeam: not found", zap.String("team", name), zap.Error(err))
-		http.Error(w, "not found", http.StatusNotFound)
+		respondError(w, h.log, "GetTeam", err)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(t)
+	writeJSON(w, http.StatusOK, t)
+}
+
+// UpdateTeam PUT /teams/{name}
+func (h *TeamsHandler) UpdateTeam(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	var in models.TeamSettings
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		h.log.Error("UpdateTeam: decode", zap.Error(err))
+		badRequest(w, "invalid body")
+		return
+	}
+	t, err := h.teams.UpdateSettings(r.Context(), name, in)
+	if err != nil {
+		respondError(w, h.log, "UpdateTeam", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
 }
 
 // ListTeams GET /teams
 func (h *TeamsHandler) ListTeams(w http.ResponseWriter, r *http.Request) {
 	list, err := h.teams.ListTeams(r.Context())
 	if err != nil {
-		h.log.Error("ListTeams: service error", zap.Error(err))
-		http.Error(w, "internal error", http.StatusInternalServerError)
+		respondError(w, h.log, "ListTeams", err)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(list)
+	writeJSON(w, http.StatusOK, list)
 }
 
-// DeleteTeam DELETE /teams/{team_name}
+// DeleteTeam DELETE /teams/{name}
 func (h *TeamsHandler) DeleteTeam(w http.ResponseWriter, r *http.Request) {
-	name := chi.URLParam(r, "team_name")
+	name := chi.URLParam(r, "name")
 	if name == "" {
-		http.Error(w, "team_name required", http.StatusBadRequest)
+		badRequest(w, "team_name required")
 		return
 	}
 	err := h.teams.DeleteTeam(r.Context(), name)
 	if err != nil {
 		// service returns ErrTeamHasMembers when FK violation occurs
-		if errors.Is(err, service.ErrTeamHasMembers) {
-			h.log.Info("DeleteTeam: has members", zap.String("team", name))
-			http.Error(w, "team has members, cannot delete", http.StatusConflict)
+		respondError(w, h.log, "DeleteTeam", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetFallbacks GET /teams/{name}/fallbacks
+func (h *TeamsHandler) GetFallbacks(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	list, err := h.teams.GetFallbacks(r.Context(), name)
+	if err != nil {
+		respondError(w, h.log, "GetFallbacks", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		TeamName  string   `json:"team_name"`
+		Fallbacks []string `json:"fallbacks"`
+	}{name, list})
+}
+
+// SetFallbacks PUT /teams/{name}/fallbacks
+func (h *TeamsHandler) SetFallbacks(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	var in struct {
+		Fallbacks []string `json:"fallbacks"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		h.log.Error("SetFallbacks: decode", zap.Error(err))
+		badRequest(w, "invalid body")
+		return
+	}
+	list, err := h.teams.SetFallbacks(r.Context(), name, in.Fallbacks)
+	if err != nil {
+		respondError(w, h.log, "SetFallbacks", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		TeamName  string   `json:"team_name"`
+		Fallbacks []string `json:"fallbacks"`
+	}{name, list})
+}
+
+// SetParent PUT /teams/{name}/parent
+func (h *TeamsHandler) SetParent(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	var in struct {
+		ParentTeam *string `json:"parent_team"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		h.log.Error("SetParent: decode", zap.Error(err))
+		badRequest(w, "invalid body")
+		return
+	}
+	t, err := h.teams.SetParent(r.Context(), name, in.ParentTeam)
+	if err != nil {
+		respondError(w, h.log, "SetParent", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+// GetTeamTree GET /teams/{name}/tree
+func (h *TeamsHandler) GetTeamTree(w http.ResponseWriter, r *http.Request) {
+	tree, err := h.teams.GetTeamTree(r.Context(), chi.URLParam(r, "name"))
+	if err != nil {
+		respondError(w, h.log, "GetTeamTree", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, tree)
+}
+
+// SetMember PUT /teams/{name}/members/{userID}
+func (h *TeamsHandler) SetMember(w http.ResponseWriter, r *http.Request) {
+	in := struct {
+		IsActive *bool   `json:"is_active"`
+		Role     *string `json:"role"`
+		Level    *string `json:"level"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		h.log.Error("SetMember: decode", zap.Error(err))
+		badRequest(w, "invalid body")
+		return
+	}
+	membership, err := h.teams.SetMember(r.Context(), service.SetMemberInput{
+		TeamName: chi.URLParam(r, "name"),
+		UserID:   chi.URLParam(r, "userID"),
+		IsActive: in.IsActive == nil || *in.IsActive,
+		Role:     in.Role,
+		Level:    in.Level,
+	})
+	if err != nil {
+		respondError(w, h.log, "SetMember", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, membership)
+}
+
+// RemoveMember DELETE /teams/{name}/members/{userID}
+func (h *TeamsHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	if err := h.teams.RemoveMember(r.Context(), chi.URLParam(r, "name"), chi.URLParam(r, "userID")); err != nil {
+		respondError(w, h.log, "RemoveMember", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetCodeOwners GET /teams/{name}/codeowners
+func (h *TeamsHandler) GetCodeOwners(w http.ResponseWriter, r *http.Request) {
+	res, err := h.teams.GetCodeOwners(r.Context(), chi.URLParam(r, "name"))
+	if err != nil {
+		respondError(w, h.log, "GetCodeOwners", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// SetCodeOwners PUT /teams/{name}/codeowners
+// Accepts the file either as the raw body or as the "file" field of a multipart form.
+func (h *TeamsHandler) SetCodeOwners(w http.ResponseWriter, r *http.Request) {
+	src, ok := uploadedFile(w, r)
+	if !ok {
+		return
+	}
+	defer src.Close()
+
+	res, err := h.teams.SetCodeOwners(r.Context(), chi.URLParam(r, "name"), src)
+	if err != nil {
+		respondError(w, h.log, "SetCodeOwners", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// defaultPairingWindow is the period the pairing matrix covers when since is not given.
+const defaultPairingWindow = 90 * 24 * time.Hour
+
+// GetPairingMatrix GET /teams/{name}/pairing-matrix?since=&until=
+// since and until are RFC 3339 times; the default is the last 90 days.
+func (h *TeamsHandler) GetPairingMatrix(w http.ResponseWriter, r *http.Request) {
+	until := time.Now()
+	if v := r.URL.Query().Get("until"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			badRequest(w, "until must be an RFC 3339 time")
+			return
+		}
+		until = t
+	}
+	since := until.Add(-defaultPairingWindow)
+	if v := r.URL.Query().Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			badRequest(w, "since must be an RFC 3339 time")
 			return
 		}
-		h.log.Error("DeleteTeam: service error", zap.Error(err))
-		http.Error(w, "internal error", http.StatusInternalServerError)
+		since = t
+	}
+
+	m, err := h.teams.PairingMatrix(r.Context(), chi.URLParam(r, "name"), since, until)
+	if err != nil {
+		respondError(w, h.log, "GetPairingMatrix", err)
 		return
 	}
-	w.WriteHeader(http.StatusNoContent)
+	writeJSON(w, http.StatusOK, m)
 }
@@ -2,8 +2,13 @@ package handlers
 
 import (
 	"encoding/json"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"pr-reviewer/internal/models"
 	"pr-reviewer/internal/service"
 
 	"github.com/go-chi/chi/v5"
@@ -28,42 +33,31 @@ func (h *UsersHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	}
 	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
 		h.log.Error("CreateUser: decode", zap.Error(err))
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		badRequest(w, "invalid request body")
 		return
 	}
 	if in.Username == "" {
-		http.Error(w, "username required", http.StatusBadRequest)
+		badRequest(w, "username required")
 		return
 	}
 
 	u, err := h.users.CreateUser(r.Context(), in.Username, in.DisplayName, in.TeamName)
 	if err != nil {
-		// сервис user возвращает строковую ошибку "team not found" при отсутствии команды
-		if err.Error() == "team not found" {
-			h.log.Info("CreateUser: team not found", zap.String("team", stringPtrToValue(in.TeamName)))
-			http.Error(w, "team not found", http.StatusBadRequest)
-			return
-		}
-		h.log.Error("CreateUser: service error", zap.Error(err))
-		http.Error(w, "internal error", http.StatusInternalServerError)
+		respondError(w, h.log, "CreateUser", err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	_ = json.NewEncoder(w).Encode(u)
+	writeJSON(w, http.StatusCreated, u)
 }
 
 // ListUsers GET /users
 func (h *UsersHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
 	list, err := h.users.ListUsers(r.Context())
 	if err != nil {
-		h.log.Error("ListUsers: service error", zap.Error(err))
-		http.Error(w, "internal error", http.StatusInternalServerError)
+		respondError(w, h.log, "ListUsers", err)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(list)
+	writeJSON(w, http.StatusOK, list)
 }
 
 // GetUser GET /users/{id}
@@ -71,13 +65,24 @@ func (h *UsersHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	u, err := h.users.GetUser(r.Context(), id)
 	if err != nil {
-		// repository/service returns non-nil error if not found — отображаем 404
-		h.log.Info("GetUser: not found", zap.String("id", id), zap.Error(err))
-		http.Error(w, "not found", http.StatusNotFound)
+		respondError(w, h.log, "GetUser", err)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(u)
+	writeJSON(w, http.StatusOK, u)
+}
+
+// ListMemberships GET /users/{id}/teams
+func (h *UsersHandler) ListMemberships(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	list, err := h.users.ListMemberships(r.Context(), id)
+	if err != nil {
+		respondError(w, h.log, "ListMemberships", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		UserID string              `json:"user_id"`
+		Teams  []models.Membership `json:"teams"`
+	}{id, list})
 }
 
 // SetIsActive POST /users/setIsActive
@@ -89,51 +94,45 @@ func (h *UsersHandler) SetIsActive(w http.ResponseWriter, r *http.Request) {
 
 	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
 		h.log.Error("SetIsActive: decode error", zap.Error(err))
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		badRequest(w, "invalid request body")
 		return
 	}
 
 	if in.UserID == "" {
-		http.Error(w, "user_id required", http.StatusBadRequest)
+		badRequest(w, "user_id required")
 		return
 	}
 
 	// вызываем сервисный метод UpdateUser (только isActive меняем)
-	err := h.users.UpdateUser(r.Context(), in.UserID, nil, &in.IsActive, nil)
+	err := h.users.UpdateUser(r.Context(), in.UserID, models.UserUpdate{IsActive: &in.IsActive})
 	if err != nil {
-		h.log.Error("SetIsActive: service error", zap.Error(err))
-		http.Error(w, "internal error", http.StatusInternalServerError)
+		respondError(w, h.log, "SetIsActive", err)
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	u, err := h.users.GetUser(r.Context(), in.UserID)
+	if err != nil {
+		respondError(w, h.log, "SetIsActive", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		User *models.User `json:"user"`
+	}{u})
 }
 
 // UpdateUser PUT /users/{id}
 func (h *UsersHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
-	var in struct {
-		DisplayName *string `json:"display_name"`
-		IsActive    *bool   `json:"is_active"`
-		TeamName    *string `json:"team_name"`
-	}
+	var in models.UserUpdate
 	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
 		h.log.Error("UpdateUser: decode", zap.Error(err))
-		http.Error(w, "invalid body", http.StatusBadRequest)
+		badRequest(w, "invalid body")
 		return
 	}
 
-	err := h.users.UpdateUser(r.Context(), id, in.DisplayName, in.IsActive, in.TeamName)
-	if err != nil {
-		// as with CreateUser, service returns "team not found" string when team absent
-		if err.Error() == "team not found" {
-			h.log.Info("UpdateUser: team not found", zap.String("team", stringPtrToValue(in.TeamName)))
-			http.Error(w, "team not found", http.StatusBadRequest)
-			return
-		}
-		// if update failed because user not found or other DB error -- respond 500 (could refine)
-		h.log.Error("UpdateUser: service error", zap.Error(err))
-		http.Error(w, "internal error", http.StatusInternalServerError)
+	if err := h.users.UpdateUser(r.Context(), id, in); err != nil {
+		respondError(w, h.log, "UpdateUser", err)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
@@ -143,22 +142,193 @@ func (h *UsersHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 func (h *UsersHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		http.Error(w, "id required", http.StatusBadRequest)
+		badRequest(w, "id required")
 		return
 	}
 	if err := h.users.DeleteUser(r.Context(), id); err != nil {
-		h.log.Error("DeleteUser: service error", zap.String("id", id), zap.Error(err))
-		// Could return 404 if not found, but repository Delete returns nil even if row missing.
-		http.Error(w, "internal error", http.StatusInternalServerError)
+		respondError(w, h.log, "DeleteUser", err)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// helper: safe deref for logging
-func stringPtrToValue(s *string) string {
-	if s == nil {
-		return ""
+type absenceBody struct {
+	Kind     models.AbsenceKind `json:"kind"`
+	StartsAt time.Time          `json:"starts_at"`
+	EndsAt   time.Time          `json:"ends_at"`
+	Note     *string            `json:"note"`
+}
+
+func (b absenceBody) absence(userID string) models.Absence {
+	return models.Absence{UserID: userID, Kind: b.Kind, StartsAt: b.StartsAt, EndsAt: b.EndsAt, Note: b.Note}
+}
+
+// ListAbsences GET /users/{id}/absences
+func (h *UsersHandler) ListAbsences(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	list, err := h.users.ListAbsences(r.Context(), id)
+	if err != nil {
+		respondError(w, h.log, "ListAbsences", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// AddAbsence POST /users/{id}/absences
+func (h *UsersHandler) AddAbsence(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var in absenceBody
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		badRequest(w, "invalid body")
+		return
+	}
+
+	a, err := h.users.AddAbsence(r.Context(), in.absence(id))
+	if err != nil {
+		respondError(w, h.log, "AddAbsence", err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, a)
+}
+
+// UpdateAbsence PUT /users/{id}/absences/{absenceID}
+func (h *UsersHandler) UpdateAbsence(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	absenceID, err := strconv.ParseInt(chi.URLParam(r, "absenceID"), 10, 64)
+	if err != nil {
+		badRequest(w, "invalid absence id")
+		return
+	}
+	var in absenceBody
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		badRequest(w, "invalid body")
+		return
+	}
+
+	a := in.absence(id)
+	a.AbsenceID = absenceID
+	updated, err := h.users.UpdateAbsence(r.Context(), a)
+	if err != nil {
+		respondError(w, h.log, "UpdateAbsence", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// DeleteAbsence DELETE /users/{id}/absences/{absenceID}
+func (h *UsersHandler) DeleteAbsence(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	absenceID, err := strconv.ParseInt(chi.URLParam(r, "absenceID"), 10, 64)
+	if err != nil {
+		badRequest(w, "invalid absence id")
+		return
+	}
+	if err := h.users.DeleteAbsence(r.Context(), id, absenceID); err != nil {
+		respondError(w, h.log, "DeleteAbsence", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type exclusionBody struct {
+	ReviewerID string  `json:"reviewer_id"`
+	Mutual     bool    `json:"mutual"`
+	Reason     *string `json:"reason"`
+}
+
+// ListExclusions GET /users/{id}/exclusions
+func (h *UsersHandler) ListExclusions(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	list, err := h.users.ListExclusions(r.Context(), id)
+	if err != nil {
+		respondError(w, h.log, "ListExclusions", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// AddExclusion POST /users/{id}/exclusions
+// The reviewer in the body will no longer be assigned to the user's pull
+// requests; with mutual set, the user not to the reviewer's either.
+func (h *UsersHandler) AddExclusion(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var in exclusionBody
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		badRequest(w, "invalid body")
+		return
+	}
+
+	e, err := h.users.AddExclusion(r.Context(), models.Exclusion{
+		AuthorID:   id,
+		ReviewerID: in.ReviewerID,
+		Mutual:     in.Mutual,
+		Reason:     in.Reason,
+	})
+	if err != nil {
+		respondError(w, h.log, "AddExclusion", err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, e)
+}
+
+// DeleteExclusion DELETE /users/{id}/exclusions/{exclusionID}
+func (h *UsersHandler) DeleteExclusion(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	exclusionID, err := strconv.ParseInt(chi.URLParam(r, "exclusionID"), 10, 64)
+	if err != nil {
+		badRequest(w, "invalid exclusion id")
+		return
+	}
+	if err := h.users.DeleteExclusion(r.Context(), id, exclusionID); err != nil {
+		respondError(w, h.log, "DeleteExclusion", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// maxUploadSize bounds uploaded files (ICS calendars, CODEOWNERS).
+const maxUploadSize = 1 << 20
+
+// uploadedFile returns the file sent either as the raw body or as the
+// "file" field of a multipart form. The caller closes it.
+func uploadedFile(w http.ResponseWriter, r *http.Request) (io.ReadCloser, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		return r.Body, true
+	}
+	f, _, err := r.FormFile("file")
+	if err != nil {
+		badRequest(w, "file required")
+		return nil, false
+	}
+	return f, true
+}
+
+// ImportCalendar POST /users/{id}/absences/import
+// Accepts the ICS either as the raw body or as the "file" field of a multipart form.
+func (h *UsersHandler) ImportCalendar(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	src, ok := uploadedFile(w, r)
+	if !ok {
+		return
+	}
+	defer src.Close()
+
+	res, err := h.users.ImportCalendar(r.Context(), id, src)
+	if err != nil {
+		respondError(w, h.log, "ImportCalendar", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// SyncCalendar POST /users/{id}/absences/sync
+func (h *UsersHandler) SyncCalendar(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	res, err := h.users.SyncCalendar(r.Context(), id)
+	if err != nil {
+		respondError(w, h.log, "SyncCalendar", err)
+		return
 	}
-	return *s
+	writeJSON(w, http.StatusOK, res)
 }
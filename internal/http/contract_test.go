@@ -0,0 +1,386 @@
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"pr-reviewer/internal/handlers"
+	http_my "pr-reviewer/internal/http"
+	"pr-reviewer/internal/repository"
+	"pr-reviewer/internal/service"
+	"pr-reviewer/internal/store"
+	"pr-reviewer/internal/store/storetest"
+)
+
+func loadSpec(t *testing.T) *openapi3.T {
+	t.Helper()
+	doc, err := openapi3.NewLoader().LoadFromFile("../../openapi.yml")
+	if err != nil {
+		t.Fatalf("load openapi.yml: %v", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		t.Fatalf("openapi.yml is invalid: %v", err)
+	}
+	return doc
+}
+
+// operations lists every documented operation as "METHOD /path".
+func operations(doc *openapi3.T) []string {
+	var ops []string
+	for path, item := range doc.Paths.Map() {
+		for method := range item.Operations() {
+			ops = append(ops, method+" "+path)
+		}
+	}
+	sort.Strings(ops)
+	return ops
+}
+
+func TestRouterServesDocumentedOperations(t *testing.T) {
+	doc := loadSpec(t)
+	router := http_my.NewRouter(
+		handlers.NewUsersHandler(nil, zap.NewNop()),
+		handlers.NewTeamsHandler(nil, zap.NewNop()),
+		handlers.NewPRHandler(nil, zap.NewNop()),
+	)
+
+	routes := map[string]bool{}
+	err := chi.Walk(router.(chi.Routes), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		routes[method+" "+route] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk routes: %v", err)
+	}
+	documented := map[string]bool{}
+	for _, op := range operations(doc) {
+		documented[op] = true
+		if !routes[op] {
+			t.Errorf("%s is documented but not routed", op)
+		}
+	}
+	for route := range routes {
+		if !documented[route] {
+			t.Errorf("%s is routed but not documented", route)
+		}
+	}
+}
+
+func newServer(s *store.Store, calendarDir string) *httptest.Server {
+	pool := s.GetPool()
+	log := zap.NewNop()
+
+	userRepo := repository.NewUserRepositoryPG(pool)
+	teamRepo := repository.NewTeamRepositoryPG(pool)
+	prRepo := repository.NewPRRepositoryPG(pool)
+	reviewRepo := repository.NewReviewRepositoryPG(pool)
+	eventRepo := repository.NewEventRepositoryPG(pool)
+	absenceRepo := repository.NewAbsenceRepositoryPG(pool)
+	exclusionRepo := repository.NewExclusionRepositoryPG(pool)
+
+	selectors := service.NewSelectorRegistry(
+		service.StrategyLeastLoaded,
+		service.NewRoundRobinSelector(teamRepo),
+		service.NewLeastLoadedSelector(),
+		service.NewRandomSelector(1),
+		service.NewLeastPairedSelector(prRepo, 30*24*time.Hour),
+	)
+	prService := service.NewPRService(s, prRepo, userRepo, teamRepo, reviewRepo, eventRepo, absenceRepo, exclusionRepo, selectors, log)
+	userService := service.NewUserService(s, userRepo, teamRepo, absenceRepo, exclusionRepo, prService, calendarDir)
+	teamService := service.NewTeamService(s, teamRepo, userRepo, prRepo, selectors, prService)
+
+	return httptest.NewServer(http_my.NewRouter(
+		handlers.NewUsersHandler(userService, log),
+		handlers.NewTeamsHandler(teamService, log),
+		handlers.NewPRHandler(prService, log),
+	))
+}
+
+// contractClient sends requests to the server, validates both sides of
+// every exchange against the spec and records which responses were seen.
+type contractClient struct {
+	t      *testing.T
+	srv    *httptest.Server
+	router routers.Router
+	seen   map[string]bool
+}
+
+func (c *contractClient) call(method, path string, body any, want int) map[string]any {
+	c.t.Helper()
+	if body == nil {
+		return c.send(method, path, "", nil, want)
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		c.t.Fatalf("marshal body: %v", err)
+	}
+	return c.send(method, path, "application/json", data, want)
+}
+
+// send is call for a body that is already encoded. It returns the decoded
+// response when that is a JSON object.
+func (c *contractClient) send(method, path, contentType string, data []byte, want int) map[string]any {
+	c.t.Helper()
+	ctx := context.Background()
+
+	req, err := http.NewRequest(method, c.srv.URL+path, bytes.NewReader(data))
+	if err != nil {
+		c.t.Fatalf("new request: %v", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	route, params, err := c.router.FindRoute(req)
+	if err != nil {
+		c.t.Fatalf("%s %s is not documented: %v", method, path, err)
+	}
+	in := &openapi3filter.RequestValidationInput{Request: req, PathParams: params, Route: route}
+	if err := openapi3filter.ValidateRequest(ctx, in); err != nil {
+		c.t.Fatalf("%s %s: request does not match the spec: %v", method, path, err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(data))
+
+	resp, err := c.srv.Client().Do(req)
+	if err != nil {
+		c.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.t.Fatalf("read response: %v", err)
+	}
+	if resp.StatusCode != want {
+		c.t.Fatalf("%s %s = %d, want %d: %s", method, path, resp.StatusCode, want, raw)
+	}
+
+	out := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: in,
+		Status:                 resp.StatusCode,
+		Header:                 resp.Header,
+		Options:                &openapi3filter.Options{IncludeResponseStatus: true},
+	}
+	out.SetBodyBytes(raw)
+	if err := openapi3filter.ValidateResponse(ctx, out); err != nil {
+		c.t.Errorf("%s %s: %d response does not match the spec: %v", method, path, resp.StatusCode, err)
+	}
+	c.seen[fmt.Sprintf("%s %s %d", route.Method, route.Path, resp.StatusCode)] = true
+
+	if len(raw) == 0 {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		c.t.Fatalf("decode response: %v", err)
+	}
+	m, _ := v.(map[string]any)
+	return m
+}
+
+// fail expects an ErrorResponse with the given status and code.
+func (c *contractClient) fail(method, path string, body any, want int, code string) {
+	c.t.Helper()
+	v := c.call(method, path, body, want)
+	e, _ := v["error"].(map[string]any)
+	if e["code"] != code {
+		c.t.Errorf("%s %s: error code = %v, want %s", method, path, e["code"], code)
+	}
+}
+
+func reviewersOf(t *testing.T, v map[string]any) []string {
+	t.Helper()
+	pr, _ := v["pr"].(map[string]any)
+	list, _ := pr["assigned_reviewers"].([]any)
+	ids := make([]string, 0, len(list))
+	for _, id := range list {
+		ids = append(ids, id.(string))
+	}
+	return ids
+}
+
+func member(id, name string) map[string]any {
+	return map[string]any{"user_id": id, "username": name, "is_active": true}
+}
+
+func TestContract(t *testing.T) {
+	doc := loadSpec(t)
+	s := storetest.New(t)
+	calendars := t.TempDir()
+	if err := os.WriteFile(filepath.Join(calendars, "u3.ics"), []byte(vacation), 0o600); err != nil {
+		t.Fatalf("write calendar: %v", err)
+	}
+	srv := newServer(s, calendars)
+	defer srv.Close()
+
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		t.Fatalf("spec router: %v", err)
+	}
+	c := &contractClient{t: t, srv: srv, router: router, seen: map[string]bool{}}
+	openapi3filter.RegisterBodyDecoder("text/calendar", openapi3filter.PlainBodyDecoder)
+
+	// teams
+	backend := map[string]any{
+		"team_name": "backend",
+		"members": []any{
+			member("u1", "Alice"), member("u2", "Bob"), member("u3", "Carol"), member("u4", "Dave"),
+		},
+	}
+	c.call("POST", "/team/add", backend, http.StatusCreated)
+	c.fail("POST", "/team/add", backend, http.StatusBadRequest, "TEAM_EXISTS")
+	c.call("POST", "/team/add", map[string]any{
+		"team_name": "solo",
+		"members":   []any{member("s1", "Sam"), member("s2", "Sasha")},
+	}, http.StatusCreated)
+	c.call("GET", "/team/get?team_name=backend", nil, http.StatusOK)
+	c.fail("GET", "/team/get?team_name=missing", nil, http.StatusNotFound, "NOT_FOUND")
+
+	c.call("POST", "/team", map[string]any{"team_name": "platform"}, http.StatusCreated)
+	c.call("GET", "/teams", nil, http.StatusOK)
+	c.call("GET", "/teams/platform", nil, http.StatusOK)
+	c.call("PUT", "/teams/platform", map[string]any{"reviewer_strategy": "random"}, http.StatusOK)
+	c.call("PUT", "/teams/platform/parent", map[string]any{"parent_team": "backend"}, http.StatusOK)
+	c.call("GET", "/teams/backend/tree", nil, http.StatusOK)
+	c.call("PUT", "/teams/platform/fallbacks", map[string]any{"fallbacks": []string{"solo"}}, http.StatusOK)
+	c.call("GET", "/teams/platform/fallbacks", nil, http.StatusOK)
+	c.call("PUT", "/teams/platform/members/u2", map[string]any{"level": "senior"}, http.StatusOK)
+	c.call("DELETE", "/teams/platform/members/u2", nil, http.StatusNoContent)
+	c.send("PUT", "/teams/backend/codeowners", "text/plain", []byte("/billing/ @Bob\n"), http.StatusOK)
+	c.call("GET", "/teams/backend/codeowners", nil, http.StatusOK)
+	c.call("POST", "/team", map[string]any{"team_name": "tmp"}, http.StatusCreated)
+	c.call("DELETE", "/teams/tmp", nil, http.StatusNoContent)
+
+	// pull requests
+	pr1 := map[string]any{"pull_request_id": "pr-1", "pull_request_name": "Add search", "author_id": "u1"}
+	created := c.call("POST", "/pullRequest/create", pr1, http.StatusCreated)
+	reviewers := reviewersOf(t, created)
+	if len(reviewers) == 0 {
+		t.Fatal("pr-1 got no reviewers")
+	}
+	c.fail("POST", "/pullRequest/create", map[string]any{
+		"pull_request_id": "pr-1", "pull_request_name": "Another search", "author_id": "u2",
+	}, http.StatusConflict, "PR_EXISTS")
+	c.fail("POST", "/pullRequest/create", map[string]any{
+		"pull_request_id": "pr-9", "pull_request_name": "Ghost", "author_id": "ghost",
+	}, http.StatusNotFound, "NOT_FOUND")
+
+	reassigned := c.call("POST", "/pullRequest/reassign", map[string]any{
+		"pull_request_id": "pr-1", "old_user_id": reviewers[0],
+	}, http.StatusOK)
+	if by, _ := reassigned["replaced_by"].(string); by == "" || by == reviewers[0] {
+		t.Errorf("replaced_by = %q", by)
+	}
+	c.fail("POST", "/pullRequest/reassign", map[string]any{
+		"pull_request_id": "pr-1", "old_user_id": "u1",
+	}, http.StatusConflict, "NOT_ASSIGNED")
+	c.fail("POST", "/pullRequest/reassign", map[string]any{
+		"pull_request_id": "missing", "old_user_id": "u2",
+	}, http.StatusNotFound, "NOT_FOUND")
+
+	c.call("POST", "/pullRequest/create", map[string]any{
+		"pull_request_id": "pr-2", "pull_request_name": "Solo fix", "author_id": "s1",
+	}, http.StatusCreated)
+	c.fail("POST", "/pullRequest/reassign", map[string]any{
+		"pull_request_id": "pr-2", "old_user_id": "s2",
+	}, http.StatusConflict, "NO_CANDIDATE")
+
+	current := reviewersOf(t, reassigned)
+	c.call("GET", "/users/getReview?user_id="+current[0], nil, http.StatusOK)
+
+	merged := c.call("POST", "/pullRequest/merge", map[string]any{"pull_request_id": "pr-1"}, http.StatusOK)
+	if pr, _ := merged["pr"].(map[string]any); pr["status"] != "MERGED" {
+		t.Errorf("status after merge = %v", pr["status"])
+	}
+	c.call("POST", "/pullRequest/merge", map[string]any{"pull_request_id": "pr-1"}, http.StatusOK)
+	c.fail("POST", "/pullRequest/merge", map[string]any{"pull_request_id": "missing"}, http.StatusNotFound, "NOT_FOUND")
+	c.fail("POST", "/pullRequest/reassign", map[string]any{
+		"pull_request_id": "pr-1", "old_user_id": current[0],
+	}, http.StatusConflict, "PR_MERGED")
+
+	c.call("POST", "/pullRequest/create", pr1, http.StatusOK)
+
+	// the life of a draft
+	c.call("POST", "/pullRequest/create", map[string]any{
+		"pull_request_id": "pr-3", "pull_request_name": "Draft", "author_id": "u1", "draft": true,
+	}, http.StatusCreated)
+	ready := c.call("POST", "/pullRequest/ready", map[string]any{"pull_request_id": "pr-3", "actor_id": "u1"}, http.StatusOK)
+	reviewers = reviewersOf(t, ready)
+	if len(reviewers) != 2 {
+		t.Fatalf("pr-3 reviewers = %v, want 2", reviewers)
+	}
+	c.call("POST", "/pullRequest/review", map[string]any{
+		"pull_request_id": "pr-3", "user_id": reviewers[0], "verdict": "APPROVED",
+	}, http.StatusCreated)
+	c.call("POST", "/pullRequest/pr-3/reviewers", map[string]any{"actor_id": "u1"}, http.StatusOK)
+	c.call("DELETE", "/pullRequest/pr-3/reviewers/"+reviewers[1]+"?actor_id=u1", nil, http.StatusOK)
+	c.call("GET", "/pullRequest/pr-3", nil, http.StatusOK)
+	c.call("POST", "/pullRequest/close", map[string]any{"pull_request_id": "pr-3"}, http.StatusOK)
+	c.call("POST", "/pullRequest/reopen", map[string]any{"pull_request_id": "pr-3"}, http.StatusOK)
+	c.call("GET", "/teams/backend/pairing-matrix", nil, http.StatusOK)
+
+	// users
+	user := c.call("POST", "/users", map[string]any{"username": "Eve", "display_name": "Eve"}, http.StatusCreated)
+	eve, _ := user["user_id"].(string)
+	c.call("GET", "/users", nil, http.StatusOK)
+	c.call("GET", "/users/"+eve, nil, http.StatusOK)
+	c.call("PUT", "/users/"+eve, map[string]any{"max_open_reviews": 3}, http.StatusNoContent)
+	c.call("PUT", "/users/"+eve, map[string]any{"clear": []string{"max_open_reviews"}}, http.StatusNoContent)
+	c.call("GET", "/users/"+eve+"/teams", nil, http.StatusOK)
+	c.call("DELETE", "/users/"+eve, nil, http.StatusNoContent)
+
+	absence := c.call("POST", "/users/u3/absences", map[string]any{
+		"kind": "VACATION", "starts_at": "2030-11-01T00:00:00Z", "ends_at": "2030-11-14T00:00:00Z",
+	}, http.StatusCreated)
+	absencePath := fmt.Sprintf("/users/u3/absences/%v", absence["absence_id"])
+	c.call("GET", "/users/u3/absences", nil, http.StatusOK)
+	c.call("PUT", absencePath, map[string]any{
+		"kind": "SICK_LEAVE", "starts_at": "2030-11-01T00:00:00Z", "ends_at": "2030-11-03T00:00:00Z",
+	}, http.StatusOK)
+	c.call("DELETE", absencePath, nil, http.StatusNoContent)
+	c.send("POST", "/users/u3/absences/import", "text/calendar", []byte(vacation), http.StatusOK)
+	c.call("PUT", "/users/u3", map[string]any{"calendar_path": "u3.ics"}, http.StatusNoContent)
+	c.call("POST", "/users/u3/absences/sync", nil, http.StatusOK)
+
+	exclusion := c.call("POST", "/users/u1/exclusions", map[string]any{"reviewer_id": "s1"}, http.StatusCreated)
+	c.call("GET", "/users/u1/exclusions", nil, http.StatusOK)
+	c.call("DELETE", fmt.Sprintf("/users/u1/exclusions/%v", exclusion["exclusion_id"]), nil, http.StatusNoContent)
+
+	c.call("POST", "/users/setIsActive", map[string]any{"user_id": "u4", "is_active": false}, http.StatusOK)
+	c.fail("POST", "/users/setIsActive", map[string]any{"user_id": "ghost", "is_active": false}, http.StatusNotFound, "NOT_FOUND")
+	c.call("POST", "/users/deactivate", map[string]any{"user_ids": []string{"u3"}}, http.StatusOK)
+
+	// every operation has been exercised at least up to its success response
+	for _, op := range operations(doc) {
+		method, path, _ := strings.Cut(op, " ")
+		ok := false
+		for status := range doc.Paths.Value(path).GetOperation(method).Responses.Map() {
+			ok = ok || strings.HasPrefix(status, "2") && c.seen[op+" "+status]
+		}
+		if !ok {
+			t.Errorf("%s is documented but no successful response was exercised", op)
+		}
+	}
+}
+
+// vacation is a calendar with a single out-of-office event.
+const vacation = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\nUID:vacation-1\r\nSUMMARY:Vacation\r\nCATEGORIES:VACATION\r\n" +
+	"DTSTART:20301201T000000Z\r\nDTEND:20301215T000000Z\r\nEND:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
@@ -19,27 +19,55 @@ func NewRouter(
 	// POST /users/setIsActive
 	r.Post("/users/setIsActive", userHandler.SetIsActive)
 	r.Get("/users/getReview", prHandler.GetPRsByReviewer)
+	r.Post("/users/deactivate", prHandler.DeactivateUsers)
 
 	r.Post("/users", userHandler.CreateUser)
 	r.Get("/users", userHandler.ListUsers)
 	r.Get("/users/{id}", userHandler.GetUser)
 	r.Put("/users/{id}", userHandler.UpdateUser)
 	r.Delete("/users/{id}", userHandler.DeleteUser)
+	r.Get("/users/{id}/teams", userHandler.ListMemberships)
+	r.Get("/users/{id}/absences", userHandler.ListAbsences)
+	r.Post("/users/{id}/absences", userHandler.AddAbsence)
+	r.Post("/users/{id}/absences/import", userHandler.ImportCalendar)
+	r.Post("/users/{id}/absences/sync", userHandler.SyncCalendar)
+	r.Put("/users/{id}/absences/{absenceID}", userHandler.UpdateAbsence)
+	r.Delete("/users/{id}/absences/{absenceID}", userHandler.DeleteAbsence)
+	r.Get("/users/{id}/exclusions", userHandler.ListExclusions)
+	r.Post("/users/{id}/exclusions", userHandler.AddExclusion)
+	r.Delete("/users/{id}/exclusions/{exclusionID}", userHandler.DeleteExclusion)
 
 	// Teams
 	// POST /team/add
 	r.Post("/team/add", teamHandler.AddTeam)
+	r.Get("/team/get", teamHandler.GetTeamByQuery)
 	r.Post("/team", teamHandler.CreateTeam)
 	r.Get("/teams", teamHandler.ListTeams)
 	r.Get("/teams/{name}", teamHandler.GetTeam)
+	r.Put("/teams/{name}", teamHandler.UpdateTeam)
 	r.Delete("/teams/{name}", teamHandler.DeleteTeam)
+	r.Get("/teams/{name}/fallbacks", teamHandler.GetFallbacks)
+	r.Put("/teams/{name}/fallbacks", teamHandler.SetFallbacks)
+	r.Put("/teams/{name}/parent", teamHandler.SetParent)
+	r.Get("/teams/{name}/tree", teamHandler.GetTeamTree)
+	r.Get("/teams/{name}/codeowners", teamHandler.GetCodeOwners)
+	r.Put("/teams/{name}/codeowners", teamHandler.SetCodeOwners)
+	r.Get("/teams/{name}/pairing-matrix", teamHandler.GetPairingMatrix)
+	r.Put("/teams/{name}/members/{userID}", teamHandler.SetMember)
+	r.Delete("/teams/{name}/members/{userID}", teamHandler.RemoveMember)
 
 	// Pull Requests
 	r.Post("/pullRequest/create", prHandler.CreatePR)
 	r.Post("/pullRequest/reassign", prHandler.ReassignReviewer)
-	r.Post("/pullRequest/{id}/merge", prHandler.MergePR)
+	r.Post("/pullRequest/merge", prHandler.MergePR)
+	r.Post("/pullRequest/review", prHandler.SubmitReview)
+	r.Post("/pullRequest/ready", prHandler.MarkReady)
+	r.Post("/pullRequest/close", prHandler.ClosePR)
+	r.Post("/pullRequest/reopen", prHandler.ReopenPR)
 
 	r.Get("/pullRequest/{id}", prHandler.GetPR)
+	r.Post("/pullRequest/{id}/reviewers", prHandler.AddReviewer)
+	r.Delete("/pullRequest/{id}/reviewers/{userID}", prHandler.RemoveReviewer)
 
 	return r
 }
@@ -0,0 +1,279 @@
+// Package ical reads the subset of iCalendar (RFC 5545) needed to import
+// out-of-office periods: VEVENTs with their start, end and classification.
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrNoCalendar = errors.New("ical: no VCALENDAR found")
+
+// Event is a parsed VEVENT. End is exclusive.
+type Event struct {
+	UID         string
+	Summary     string
+	Categories  []string
+	BusyStatus  string
+	Status      string
+	Transparent bool
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+}
+
+// OutOfOffice reports whether the event marks its owner as unavailable:
+// an Outlook OOF busy status or an out-of-office like category.
+func (e Event) OutOfOffice() bool {
+	if strings.EqualFold(e.Status, "CANCELLED") {
+		return false
+	}
+	if strings.EqualFold(e.BusyStatus, "OOF") {
+		return true
+	}
+	for _, c := range e.Categories {
+		switch strings.ToLower(c) {
+		case "out of office", "out-of-office", "ooo", "vacation", "holiday", "sick", "sick leave":
+			return true
+		}
+	}
+	return false
+}
+
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Parse reads all VEVENTs of the calendar. Events without a usable start
+// are skipped; a missing end defaults to one day for all-day events and
+// to the start otherwise. All-day dates and floating times are read in loc,
+// UTC when loc is nil. Properties of components nested in a VEVENT, such as
+// VALARM, are ignored.
+func Parse(r io.Reader, loc *time.Location) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	var events []Event
+	var cur *Event
+	var props []property
+	var open []string // names of the components being read, innermost last
+	seenCalendar := false
+	for _, line := range lines {
+		p, ok := parseLine(line)
+		if !ok {
+			continue
+		}
+		switch p.name {
+		case "BEGIN":
+			name := strings.ToUpper(p.value)
+			open = append(open, name)
+			switch name {
+			case "VCALENDAR":
+				seenCalendar = true
+			case "VEVENT":
+				cur = &Event{}
+				props = props[:0]
+			}
+		case "END":
+			name := strings.ToUpper(p.value)
+			i := lastIndex(open, name)
+			if i < 0 {
+				continue // END without BEGIN
+			}
+			open = open[:i]
+			if name == "VEVENT" && cur != nil {
+				if err := build(cur, props, loc); err == nil {
+					events = append(events, *cur)
+				}
+				cur = nil
+			}
+		default:
+			if cur != nil && open[len(open)-1] == "VEVENT" {
+				props = append(props, p)
+			}
+		}
+	}
+	if !seenCalendar {
+		return nil, ErrNoCalendar
+	}
+	return events, nil
+}
+
+func lastIndex(names []string, name string) int {
+	for i := len(names) - 1; i >= 0; i-- {
+		if names[i] == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// unfold joins continuation lines, which start with a space or a tab.
+func unfold(r io.Reader) ([]string, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	var lines []string
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, sc.Err()
+}
+
+// parseLine splits "NAME;PARAM=VALUE:value" into its parts.
+func parseLine(line string) (property, bool) {
+	colon := valueStart(line)
+	if colon < 0 {
+		return property{}, false
+	}
+	head, value := line[:colon], line[colon+1:]
+	parts := strings.Split(head, ";")
+	p := property{name: strings.ToUpper(parts[0]), params: make(map[string]string), value: value}
+	for _, param := range parts[1:] {
+		k, v, ok := strings.Cut(param, "=")
+		if ok {
+			p.params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+	}
+	return p, true
+}
+
+// valueStart finds the colon ending the property head, skipping quoted parameter values.
+func valueStart(line string) int {
+	quoted := false
+	for i, c := range line {
+		switch c {
+		case '"':
+			quoted = !quoted
+		case ':':
+			if !quoted {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func build(e *Event, props []property, loc *time.Location) error {
+	var duration time.Duration
+	hasEnd, hasDuration := false, false
+	for _, p := range props {
+		var err error
+		switch p.name {
+		case "UID":
+			e.UID = p.value
+		case "SUMMARY":
+			e.Summary = unescape(p.value)
+		case "CATEGORIES":
+			for _, c := range strings.Split(p.value, ",") {
+				if c = strings.TrimSpace(unescape(c)); c != "" {
+					e.Categories = append(e.Categories, c)
+				}
+			}
+		case "X-MICROSOFT-CDO-BUSYSTATUS":
+			e.BusyStatus = p.value
+		case "STATUS":
+			e.Status = p.value
+		case "TRANSP":
+			e.Transparent = strings.EqualFold(p.value, "TRANSPARENT")
+		case "DTSTART":
+			e.Start, e.AllDay, err = parseTime(p, loc)
+		case "DTEND":
+			e.End, _, err = parseTime(p, loc)
+			hasEnd = true
+		case "DURATION":
+			duration, err = parseDuration(p.value)
+			hasDuration = true
+		}
+		if err != nil {
+			return fmt.Errorf("ical: %s: %w", p.name, err)
+		}
+	}
+	if e.Start.IsZero() {
+		return errors.New("ical: event without DTSTART")
+	}
+	switch {
+	case hasEnd:
+	case hasDuration:
+		e.End = e.Start.Add(duration)
+	case e.AllDay:
+		e.End = e.Start.AddDate(0, 0, 1)
+	default:
+		e.End = e.Start
+	}
+	return nil
+}
+
+// parseTime reads a DATE or DATE-TIME value; def is the zone of values
+// without TZID or UTC marker.
+func parseTime(p property, def *time.Location) (time.Time, bool, error) {
+	if strings.EqualFold(p.params["VALUE"], "DATE") || len(p.value) == len("20060102") {
+		t, err := time.ParseInLocation("20060102", p.value, location(p, def))
+		return t, true, err
+	}
+	if strings.HasSuffix(p.value, "Z") {
+		t, err := time.Parse("20060102T150405Z", p.value)
+		return t, false, err
+	}
+	t, err := time.ParseInLocation("20060102T150405", p.value, location(p, def))
+	return t, false, err
+}
+
+// location resolves TZID; unknown zones and floating times are read in def.
+func location(p property, def *time.Location) *time.Location {
+	if tzid := p.params["TZID"]; tzid != "" {
+		if loc, err := time.LoadLocation(tzid); err == nil {
+			return loc
+		}
+	}
+	return def
+}
+
+var durationRe = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseDuration reads an RFC 5545 duration such as P2W, P1D or PT1H30M.
+func parseDuration(s string) (time.Duration, error) {
+	m := durationRe.FindStringSubmatch(s)
+	if m == nil || s == "P" || strings.HasSuffix(s, "T") {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var d time.Duration
+	for i, unit := range units {
+		if m[i+2] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(m[i+2])
+		if err != nil {
+			return 0, err
+		}
+		d += time.Duration(n) * unit
+	}
+	if m[1] == "-" {
+		d = -d
+	}
+	return d, nil
+}
+
+var unescaper = strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+
+func unescape(s string) string {
+	return unescaper.Replace(s)
+}
@@ -0,0 +1,237 @@
+package ical
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func calendar(lines ...string) string {
+	return "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n" + strings.Join(lines, "\r\n") + "\r\nEND:VCALENDAR\r\n"
+}
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s is not available: %v", name, err)
+	}
+	return loc
+}
+
+func TestParse(t *testing.T) {
+	moscow := mustLoad(t, "Europe/Moscow")
+	berlin := mustLoad(t, "Europe/Berlin")
+
+	tests := []struct {
+		name string
+		ics  string
+		loc  *time.Location
+		want []Event
+	}{
+		{
+			name: "utc times",
+			ics: calendar(
+				"BEGIN:VEVENT",
+				"UID:1",
+				"SUMMARY:Vacation",
+				"DTSTART:20250301T090000Z",
+				"DTEND:20250301T170000Z",
+				"END:VEVENT",
+			),
+			want: []Event{{
+				UID:     "1",
+				Summary: "Vacation",
+				Start:   time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC),
+				End:     time.Date(2025, 3, 1, 17, 0, 0, 0, time.UTC),
+			}},
+		},
+		{
+			name: "all-day event in the user's zone",
+			ics: calendar(
+				"BEGIN:VEVENT",
+				"DTSTART;VALUE=DATE:20250301",
+				"DTEND;VALUE=DATE:20250303",
+				"END:VEVENT",
+			),
+			loc: moscow,
+			want: []Event{{
+				Start:  time.Date(2025, 3, 1, 0, 0, 0, 0, moscow),
+				End:    time.Date(2025, 3, 3, 0, 0, 0, 0, moscow),
+				AllDay: true,
+			}},
+		},
+		{
+			name: "all-day event without end lasts one day",
+			ics: calendar(
+				"BEGIN:VEVENT",
+				"DTSTART;VALUE=DATE:20250301",
+				"END:VEVENT",
+			),
+			want: []Event{{
+				Start:  time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+				End:    time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC),
+				AllDay: true,
+			}},
+		},
+		{
+			name: "TZID wins over the user's zone",
+			ics: calendar(
+				"BEGIN:VEVENT",
+				"DTSTART;TZID=Europe/Berlin:20250301T090000",
+				"DURATION:PT1H30M",
+				"END:VEVENT",
+			),
+			loc: moscow,
+			want: []Event{{
+				Start: time.Date(2025, 3, 1, 9, 0, 0, 0, berlin),
+				End:   time.Date(2025, 3, 1, 10, 30, 0, 0, berlin),
+			}},
+		},
+		{
+			name: "alarm properties are ignored",
+			ics: calendar(
+				"BEGIN:VEVENT",
+				"UID:2",
+				"SUMMARY:Sick leave",
+				"DTSTART:20250301T090000Z",
+				"BEGIN:VALARM",
+				"ACTION:EMAIL",
+				"SUMMARY:Reminder",
+				"TRIGGER:-PT15M",
+				"DURATION:PT5M",
+				"END:VALARM",
+				"DTEND:20250301T170000Z",
+				"END:VEVENT",
+			),
+			want: []Event{{
+				UID:     "2",
+				Summary: "Sick leave",
+				Start:   time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC),
+				End:     time.Date(2025, 3, 1, 17, 0, 0, 0, time.UTC),
+			}},
+		},
+		{
+			name: "time zone definitions are not events",
+			ics: calendar(
+				"BEGIN:VTIMEZONE",
+				"TZID:Europe/Berlin",
+				"BEGIN:STANDARD",
+				"DTSTART:19701025T030000",
+				"END:STANDARD",
+				"END:VTIMEZONE",
+				"BEGIN:VEVENT",
+				"DTSTART:20250301T090000Z",
+				"END:VEVENT",
+			),
+			want: []Event{{
+				Start: time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC),
+				End:   time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC),
+			}},
+		},
+		{
+			name: "folded lines and escapes",
+			ics: calendar(
+				"BEGIN:VEVENT",
+				"SUMMARY:Out\\, of",
+				"  office",
+				"CATEGORIES:Vacation,OOO",
+				"DTSTART:20250301T090000Z",
+				"END:VEVENT",
+			),
+			want: []Event{{
+				Summary:    "Out, of office",
+				Categories: []string{"Vacation", "OOO"},
+				Start:      time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC),
+				End:        time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC),
+			}},
+		},
+		{
+			name: "event without start is skipped",
+			ics: calendar(
+				"BEGIN:VEVENT",
+				"SUMMARY:Broken",
+				"END:VEVENT",
+			),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(strings.NewReader(tt.ics), tt.loc)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d events, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i := range got {
+				assertEvent(t, got[i], tt.want[i])
+			}
+		})
+	}
+}
+
+func assertEvent(t *testing.T, got, want Event) {
+	t.Helper()
+	if got.UID != want.UID || got.Summary != want.Summary || got.AllDay != want.AllDay {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if strings.Join(got.Categories, ",") != strings.Join(want.Categories, ",") {
+		t.Errorf("categories = %q, want %q", got.Categories, want.Categories)
+	}
+	if !got.Start.Equal(want.Start) {
+		t.Errorf("start = %v, want %v", got.Start, want.Start)
+	}
+	if !got.End.Equal(want.End) {
+		t.Errorf("end = %v, want %v", got.End, want.End)
+	}
+}
+
+func TestParseWithoutCalendar(t *testing.T) {
+	_, err := Parse(strings.NewReader("BEGIN:VEVENT\r\nEND:VEVENT\r\n"), nil)
+	if !errors.Is(err, ErrNoCalendar) {
+		t.Fatalf("err = %v, want ErrNoCalendar", err)
+	}
+}
+
+func TestOutOfOffice(t *testing.T) {
+	tests := []struct {
+		name string
+		e    Event
+		want bool
+	}{
+		{"busy status", Event{BusyStatus: "OOF"}, true},
+		{"category", Event{Categories: []string{"Vacation"}}, true},
+		{"cancelled", Event{BusyStatus: "OOF", Status: "CANCELLED"}, false},
+		{"meeting", Event{BusyStatus: "BUSY", Categories: []string{"Meeting"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.e.OutOfOffice(); got != tt.want {
+				t.Errorf("OutOfOffice() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+		ok   bool
+	}{
+		{"P2W", 14 * 24 * time.Hour, true},
+		{"P1DT2H", 26 * time.Hour, true},
+		{"-PT15M", -15 * time.Minute, true},
+		{"P", 0, false},
+		{"PT", 0, false},
+		{"1H", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := parseDuration(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseDuration(%q) = %v, %v; want %v, ok=%v", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
@@ -3,16 +3,174 @@ package models
 import "time"
 
 type User struct {
-	UserID      string    `json:"user_id" db:"user_id"`
-	Username    string    `json:"username" db:"username"`
-	DisplayName string    `json:"display_name" db:"display_name"`
-	IsActive    bool      `json:"is_active" db:"is_active"`
-	TeamName    *string   `json:"team_name" db:"team_name"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UserID      string   `json:"user_id" db:"user_id"`
+	Username    string   `json:"username" db:"username"`
+	DisplayName string   `json:"display_name" db:"display_name"`
+	IsActive    bool     `json:"is_active" db:"is_active"`
+	TeamName    *string  `json:"team_name"` // the team joined first
+	Teams       []string `json:"teams"`
+	Skills      []string `json:"skills"`
+	// Level is the seniority in a team; set only when users are listed by team.
+	Level          string    `json:"level,omitempty"`
+	MaxOpenReviews *int      `json:"max_open_reviews" db:"max_open_reviews"`
+	IsAdmin        bool      `json:"is_admin" db:"is_admin"`
+	CalendarPath   *string   `json:"calendar_path,omitempty" db:"calendar_path"`
+	Timezone       *string   `json:"timezone,omitempty" db:"timezone"`
+	WorkStart      *string   `json:"work_start,omitempty" db:"work_start"`
+	WorkEnd        *string   `json:"work_end,omitempty" db:"work_end"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// UserUpdate lists the user fields to change. Nil fields are left untouched;
+// Skills replaces all skills of the user. Clear names, by their JSON names,
+// the optional fields to reset: max_open_reviews, calendar_path, timezone,
+// work_start and work_end.
+type UserUpdate struct {
+	DisplayName    *string  `json:"display_name"`
+	IsActive       *bool    `json:"is_active"`
+	MaxOpenReviews *int     `json:"max_open_reviews"`
+	IsAdmin        *bool    `json:"is_admin"`
+	CalendarPath   *string  `json:"calendar_path"`
+	Timezone       *string  `json:"timezone"`
+	WorkStart      *string  `json:"work_start"`
+	WorkEnd        *string  `json:"work_end"`
+	Skills         []string `json:"skills"`
+	Clear          []string `json:"clear"`
 }
 
 type Team struct {
-	TeamName  string    `json:"team_name" db:"team_name"`
-	Desc      *string   `json:"description" db:"description"`
+	TeamName   string  `json:"team_name" db:"team_name"`
+	Desc       *string `json:"description" db:"description"`
+	ParentTeam *string `json:"parent_team" db:"parent_team"`
+	TeamSettings
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 }
+
+// TeamMember is a user as listed inside a team. IsActive is false when
+// either the user or their membership in the team is inactive.
+type TeamMember struct {
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+	IsActive bool   `json:"is_active"`
+	Role     string `json:"role,omitempty"`
+	Level    string `json:"level,omitempty"`
+}
+
+const (
+	RoleMember = "member"
+	RoleLead   = "lead"
+)
+
+// Seniority levels of team members, from least to most senior.
+const (
+	LevelJunior = "junior"
+	LevelMiddle = "middle"
+	LevelSenior = "senior"
+	LevelLead   = "lead"
+)
+
+// Membership links a user to one of their teams.
+type Membership struct {
+	TeamName string    `json:"team_name" db:"team_name"`
+	UserID   string    `json:"user_id" db:"user_id"`
+	IsActive bool      `json:"is_active" db:"is_active"`
+	Role     string    `json:"role" db:"role"`
+	Level    string    `json:"level" db:"level"`
+	JoinedAt time.Time `json:"joined_at" db:"joined_at"`
+}
+
+// TeamDetails is a team together with its members.
+type TeamDetails struct {
+	Team
+	Members []TeamMember `json:"members"`
+}
+
+// TeamNode is a team with its members and sub-teams.
+type TeamNode struct {
+	TeamDetails
+	Children []TeamNode `json:"children"`
+}
+
+// TeamSettings holds team-level options that can be changed after creation.
+// Nil fields are left untouched on update and inherited from the parent team.
+// An empty ReviewerStrategy clears the team's own strategy.
+type TeamSettings struct {
+	ReviewerStrategy        *string `json:"reviewer_strategy" db:"reviewer_strategy"`
+	MinApprovals            *int    `json:"min_approvals" db:"min_approvals"`
+	BlockOnChangesRequested *bool   `json:"block_on_changes_requested" db:"block_on_changes_requested"`
+	ForbidSelfApproval      *bool   `json:"forbid_self_approval" db:"forbid_self_approval"`
+	OnReviewerDeactivation  *string `json:"on_reviewer_deactivation" db:"on_reviewer_deactivation"`
+	DefaultReviewers        *int    `json:"default_reviewers" db:"default_reviewers"`
+	MinReviewers            *int    `json:"min_reviewers" db:"min_reviewers"`
+	MaxReviewers            *int    `json:"max_reviewers" db:"max_reviewers"`
+	MinSeniorReviewers      *int    `json:"min_senior_reviewers" db:"min_senior_reviewers"`
+	JuniorNeedsLead         *bool   `json:"junior_needs_lead" db:"junior_needs_lead"`
+}
+
+// CodeOwnersRule is a parsed line of a team's CODEOWNERS file.
+type CodeOwnersRule struct {
+	Pattern string   `json:"pattern"`
+	Owners  []string `json:"owners"`
+	Line    int      `json:"line"`
+}
+
+// ReviewerBounds is the effective number of reviewers a team's PRs get by
+// default and the range a PR may be adjusted within.
+type ReviewerBounds struct {
+	Default int `json:"default"`
+	Min     int `json:"min"`
+	Max     int `json:"max"`
+}
+
+// MergePolicy is the effective set of rules checked before a merge.
+type MergePolicy struct {
+	MinApprovals            int  `json:"min_approvals"`
+	BlockOnChangesRequested bool `json:"block_on_changes_requested"`
+	ForbidSelfApproval      bool `json:"forbid_self_approval"`
+}
+
+type AbsenceKind string
+
+const (
+	AbsenceVacation  AbsenceKind = "VACATION"
+	AbsenceSickLeave AbsenceKind = "SICK_LEAVE"
+	AbsenceOther     AbsenceKind = "OTHER"
+)
+
+func (k AbsenceKind) Valid() bool {
+	switch k {
+	case AbsenceVacation, AbsenceSickLeave, AbsenceOther:
+		return true
+	}
+	return false
+}
+
+const (
+	AbsenceSourceManual = "manual"
+	AbsenceSourceICS    = "ics"
+)
+
+// Absence is a period [StartsAt, EndsAt) in which the user is not picked as a reviewer.
+// Absences imported from a calendar keep the event UID in ExternalUID.
+type Absence struct {
+	AbsenceID   int64       `json:"absence_id" db:"absence_id"`
+	UserID      string      `json:"user_id" db:"user_id"`
+	Kind        AbsenceKind `json:"kind" db:"kind"`
+	StartsAt    time.Time   `json:"starts_at" db:"starts_at"`
+	EndsAt      time.Time   `json:"ends_at" db:"ends_at"`
+	Note        *string     `json:"note,omitempty" db:"note"`
+	Source      string      `json:"source" db:"source"`
+	ExternalUID *string     `json:"external_uid,omitempty" db:"external_uid"`
+	CreatedAt   time.Time   `json:"created_at" db:"created_at"`
+}
+
+// Exclusion forbids ReviewerID to review pull requests of AuthorID, and
+// AuthorID those of ReviewerID as well when Mutual is set.
+type Exclusion struct {
+	ExclusionID int64     `json:"exclusion_id" db:"exclusion_id"`
+	AuthorID    string    `json:"author_id" db:"author_id"`
+	ReviewerID  string    `json:"reviewer_id" db:"reviewer_id"`
+	Mutual      bool      `json:"mutual" db:"mutual"`
+	Reason      *string   `json:"reason,omitempty" db:"reason"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
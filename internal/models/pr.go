@@ -1,22 +1,47 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type PRStatus string
 
 const (
+	PRStatusDraft  PRStatus = "DRAFT"
 	PRStatusOpen   PRStatus = "OPEN"
 	PRStatusMerged PRStatus = "MERGED"
+	PRStatusClosed PRStatus = "CLOSED"
 )
 
 type PullRequest struct {
-	PullRequestID   string     `json:"pull_request_id" db:"pull_request_id"`
-	PullRequestName string     `json:"pull_request_name" db:"pull_request_name"`
-	AuthorID        string     `json:"author_id" db:"author_id"`
-	TeamName        string     `json:"team_name" db:"team_name"`
-	Status          PRStatus   `json:"status" db:"status"`
-	CreatedAt       time.Time  `json:"created_at" db:"createdat"`
-	MergedAt        *time.Time `json:"merged_at,omitempty" db:"mergedat"`
+	PullRequestID     string     `json:"pull_request_id" db:"pull_request_id"`
+	PullRequestName   string     `json:"pull_request_name" db:"pull_request_name"`
+	AuthorID          string     `json:"author_id" db:"author_id"`
+	TeamName          string     `json:"team_name" db:"team_name"`
+	Status            PRStatus   `json:"status" db:"status"`
+	AssignedReviewers []string   `json:"assigned_reviewers" db:"-"`
+	ReviewerCount     int        `json:"reviewer_count" db:"reviewer_count"`
+	CreatedAt         *time.Time `json:"createdAt" db:"created_at"`
+	MergedAt          *time.Time `json:"mergedAt" db:"merged_at"`
+	ClosedAt          *time.Time `json:"closedAt,omitempty" db:"closed_at"`
+}
+
+// PullRequestShort is the compact form used in reviewer listings.
+type PullRequestShort struct {
+	PullRequestID   string   `json:"pull_request_id"`
+	PullRequestName string   `json:"pull_request_name"`
+	AuthorID        string   `json:"author_id"`
+	Status          PRStatus `json:"status"`
+}
+
+func (pr PullRequest) Short() PullRequestShort {
+	return PullRequestShort{
+		PullRequestID:   pr.PullRequestID,
+		PullRequestName: pr.PullRequestName,
+		AuthorID:        pr.AuthorID,
+		Status:          pr.Status,
+	}
 }
 
 type PRReviewer struct {
@@ -24,3 +49,133 @@ type PRReviewer struct {
 	ReviewerID    string    `json:"reviewer_id" db:"reviewer_id"`
 	AssignedAt    time.Time `json:"assigned_at" db:"assigned_at"`
 }
+
+// ReviewerChange is a reviewer swap on a pull request. NewReviewerID is
+// empty when no replacement could be found.
+type ReviewerChange struct {
+	PullRequestID string `json:"pull_request_id"`
+	OldReviewerID string `json:"old_reviewer_id"`
+	NewReviewerID string `json:"new_reviewer_id,omitempty"`
+}
+
+// Candidate is a potential reviewer with the number of OPEN pull requests
+// they currently review. Fallback marks members of a fallback team,
+// Escalated members of a parent team. CodeOwner is set for reviewers
+// picked because they own paths the PR touches.
+type Candidate struct {
+	User
+	OpenReviews int         `json:"open_reviews"`
+	Fallback    bool        `json:"fallback,omitempty"`
+	Escalated   bool        `json:"escalated,omitempty"`
+	CodeOwner   *OwnerMatch `json:"code_owner,omitempty"`
+}
+
+// OwnerMatch explains why a reviewer was picked as a code owner: the
+// CODEOWNERS rule, the owner entry naming them and the touched paths it owns.
+type OwnerMatch struct {
+	Pattern string   `json:"pattern"`
+	Line    int      `json:"line"`
+	Owner   string   `json:"owner"`
+	Paths   []string `json:"paths"`
+}
+
+// QueuedReview is a pull request still waiting for reviewers because every
+// candidate was at capacity when it was created.
+type QueuedReview struct {
+	PullRequestID    string    `json:"pull_request_id" db:"pull_request_id"`
+	MissingReviewers int       `json:"missing_reviewers" db:"missing_reviewers"`
+	QueuedAt         time.Time `json:"queued_at" db:"queued_at"`
+}
+
+type ReviewVerdict string
+
+const (
+	VerdictApproved         ReviewVerdict = "APPROVED"
+	VerdictChangesRequested ReviewVerdict = "CHANGES_REQUESTED"
+	VerdictCommented        ReviewVerdict = "COMMENTED"
+)
+
+func (v ReviewVerdict) Valid() bool {
+	switch v {
+	case VerdictApproved, VerdictChangesRequested, VerdictCommented:
+		return true
+	}
+	return false
+}
+
+// Review is a verdict submitted by an assigned reviewer.
+type Review struct {
+	ReviewID      int64         `json:"review_id" db:"review_id"`
+	PullRequestID string        `json:"pull_request_id" db:"pull_request_id"`
+	ReviewerID    string        `json:"reviewer_id" db:"reviewer_id"`
+	Verdict       ReviewVerdict `json:"verdict" db:"verdict"`
+	Message       *string       `json:"message,omitempty" db:"message"`
+	CreatedAt     time.Time     `json:"created_at" db:"created_at"`
+}
+
+// PullRequestDetails is a pull request with its reviewers, reviews, event
+// history and the areas it needs a reviewer for.
+type PullRequestDetails struct {
+	PR             *PullRequest `json:"pr"`
+	Reviewers      []User       `json:"reviewers"`
+	Reviews        []Review     `json:"reviews"`
+	History        []PREvent    `json:"history"`
+	RequiredSkills []string     `json:"required_skills"`
+}
+
+// ReviewAssignment is a pull request as seen by one of its reviewers,
+// with the reviewer's latest verdict if any.
+type ReviewAssignment struct {
+	PullRequestShort
+	Verdict    *ReviewVerdict `json:"verdict,omitempty"`
+	ReviewedAt *time.Time     `json:"reviewed_at,omitempty"`
+}
+
+type PREventType string
+
+const (
+	EventMerged         PREventType = "MERGED"
+	EventForceMerged    PREventType = "FORCE_MERGED"
+	EventReadyForReview PREventType = "READY_FOR_REVIEW"
+	EventClosed         PREventType = "CLOSED"
+	EventReopened       PREventType = "REOPENED"
+	// a reviewer was replaced because they became inactive
+	EventReviewerReplaced PREventType = "REVIEWER_REPLACED"
+	// an inactive reviewer is still assigned and needs attention
+	EventReviewerInactive PREventType = "REVIEWER_INACTIVE"
+	EventReviewerAdded    PREventType = "REVIEWER_ADDED"
+	EventReviewerRemoved  PREventType = "REVIEWER_REMOVED"
+	// an inactive reviewer stays assigned because the team leaves their reviews
+	EventReviewerKept PREventType = "REVIEWER_KEPT"
+	// a reviewer was replaced on request through /pullRequest/reassign
+	EventReviewerReassigned PREventType = "REVIEWER_REASSIGNED"
+)
+
+// PREvent is an entry of the pull request history. Details holds
+// event specific data as a JSON object.
+type PREvent struct {
+	EventID       int64           `json:"event_id" db:"event_id"`
+	PullRequestID string          `json:"pull_request_id" db:"pull_request_id"`
+	Type          PREventType     `json:"event_type" db:"event_type"`
+	ActorID       *string         `json:"actor_id" db:"actor_id"`
+	Details       json.RawMessage `json:"details,omitempty" db:"details"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+}
+
+// Pairing is how many pull requests of an author a reviewer was assigned to.
+type Pairing struct {
+	AuthorID   string `json:"author_id" db:"author_id"`
+	ReviewerID string `json:"reviewer_id" db:"reviewer_id"`
+	Count      int    `json:"count" db:"count"`
+}
+
+// PairingMatrix holds the author x reviewer counts of a team's pull requests
+// created in [Since, Until). Counts[i][j] is for Authors[i] and Reviewers[j].
+type PairingMatrix struct {
+	TeamName  string    `json:"team_name"`
+	Since     time.Time `json:"since"`
+	Until     time.Time `json:"until"`
+	Authors   []string  `json:"authors"`
+	Reviewers []string  `json:"reviewers"`
+	Counts    [][]int   `json:"counts"`
+}
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"pr-reviewer/internal/apperr"
+	"pr-reviewer/internal/models"
+)
+
+var ErrAbsenceNotFound = apperr.New(apperr.CodeNotFound, "absence not found")
+
+type AbsenceRepository interface {
+	Create(ctx context.Context, a *models.Absence) error
+	// GetByID returns the absence only when it belongs to userID.
+	GetByID(ctx context.Context, userID string, id int64) (*models.Absence, error)
+	ListByUser(ctx context.Context, userID string) ([]models.Absence, error)
+	Update(ctx context.Context, a *models.Absence) error
+	Delete(ctx context.Context, userID string, id int64) error
+	// ReplaceImported swaps all absences of the user from source for list.
+	ReplaceImported(ctx context.Context, userID string, source string, list []models.Absence) error
+	// AbsentAt returns which of the given users are absent at the moment at.
+	AbsentAt(ctx context.Context, userIDs []string, at time.Time) (map[string]bool, error)
+}
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/store"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const absenceColumns = `absence_id, user_id, kind, starts_at, ends_at, note, source, external_uid, created_at`
+
+type absenceRepoPG struct {
+	p *pgxpool.Pool
+}
+
+func NewAbsenceRepositoryPG(p *pgxpool.Pool) AbsenceRepository {
+	return &absenceRepoPG{p: p}
+}
+
+// db runs queries inside the transaction carried by ctx, if any.
+func (r *absenceRepoPG) db(ctx context.Context) store.DBTX {
+	return store.Conn(ctx, r.p)
+}
+
+func scanAbsence(row pgx.Row, a *models.Absence) error {
+	return row.Scan(&a.AbsenceID, &a.UserID, &a.Kind, &a.StartsAt, &a.EndsAt, &a.Note, &a.Source, &a.ExternalUID, &a.CreatedAt)
+}
+
+func (r *absenceRepoPG) Create(ctx context.Context, a *models.Absence) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	query := `
+		INSERT INTO user_absences (user_id, kind, starts_at, ends_at, note)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + absenceColumns
+	err := scanAbsence(r.db(ctx).QueryRow(ctx, query, a.UserID, a.Kind, a.StartsAt, a.EndsAt, a.Note), a)
+	return translateError(err, nil)
+}
+
+func (r *absenceRepoPG) GetByID(ctx context.Context, userID string, id int64) (*models.Absence, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	query := `SELECT ` + absenceColumns + ` FROM user_absences WHERE absence_id = $1 AND user_id = $2`
+	var a models.Absence
+	if err := scanAbsence(r.db(ctx).QueryRow(ctx, query, id, userID), &a); err != nil {
+		return nil, translateError(err, ErrAbsenceNotFound)
+	}
+	return &a, nil
+}
+
+func (r *absenceRepoPG) ListByUser(ctx context.Context, userID string) ([]models.Absence, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	query := `SELECT ` + absenceColumns + ` FROM user_absences WHERE user_id = $1 ORDER BY starts_at, absence_id`
+	rows, err := r.db(ctx).Query(ctx, query, userID)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+
+	list := make([]models.Absence, 0)
+	for rows.Next() {
+		var a models.Absence
+		if err := scanAbsence(rows, &a); err != nil {
+			return nil, err
+		}
+		list = append(list, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, translateError(err, nil)
+	}
+
+	return list, nil
+}
+
+func (r *absenceRepoPG) Update(ctx context.Context, a *models.Absence) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	query := `
+		UPDATE user_absences SET kind = $1, starts_at = $2, ends_at = $3, note = $4
+		WHERE absence_id = $5 AND user_id = $6
+		RETURNING ` + absenceColumns
+	err := scanAbsence(r.db(ctx).QueryRow(ctx, query, a.Kind, a.StartsAt, a.EndsAt, a.Note, a.AbsenceID, a.UserID), a)
+	return translateError(err, ErrAbsenceNotFound)
+}
+
+func (r *absenceRepoPG) Delete(ctx context.Context, userID string, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tag, err := r.db(ctx).Exec(ctx, `DELETE FROM user_absences WHERE absence_id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return translateError(err, nil)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAbsenceNotFound
+	}
+	return nil
+}
+
+func (r *absenceRepoPG) ReplaceImported(ctx context.Context, userID string, source string, list []models.Absence) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := r.db(ctx).Exec(ctx, `DELETE FROM user_absences WHERE user_id = $1 AND source = $2`, userID, source)
+	if err != nil {
+		return translateError(err, nil)
+	}
+	if len(list) == 0 {
+		return nil
+	}
+
+	kinds := make([]string, 0, len(list))
+	starts := make([]time.Time, 0, len(list))
+	ends := make([]time.Time, 0, len(list))
+	notes := make([]*string, 0, len(list))
+	uids := make([]*string, 0, len(list))
+	for _, a := range list {
+		kinds = append(kinds, string(a.Kind))
+		starts = append(starts, a.StartsAt)
+		ends = append(ends, a.EndsAt)
+		notes = append(notes, a.Note)
+		uids = append(uids, a.ExternalUID)
+	}
+
+	query := `
+		INSERT INTO user_absences (user_id, source, kind, starts_at, ends_at, note, external_uid)
+		SELECT $1, $2, a.kind, a.starts_at, a.ends_at, a.note, a.external_uid
+		FROM unnest($3::text[], $4::timestamptz[], $5::timestamptz[], $6::text[], $7::text[])
+			AS a(kind, starts_at, ends_at, note, external_uid)
+	`
+	_, err = r.db(ctx).Exec(ctx, query, userID, source, kinds, starts, ends, notes, uids)
+	return translateError(err, nil)
+}
+
+func (r *absenceRepoPG) AbsentAt(ctx context.Context, userIDs []string, at time.Time) (map[string]bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	query := `
+		SELECT DISTINCT user_id FROM user_absences
+		WHERE user_id = ANY($1) AND starts_at <= $2 AND ends_at > $2
+	`
+	rows, err := r.db(ctx).Query(ctx, query, userIDs, at)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+
+	result := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		result[id] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, translateError(err, nil)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"errors"
+
+	"pr-reviewer/internal/apperr"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// translateError converts pgx errors into domain errors. notFound is returned
+// for pgx.ErrNoRows; pass nil when no rows is not expected.
+func translateError(err error, notFound error) error {
+	if err == nil {
+		return nil
+	}
+	if notFound != nil && errors.Is(err, pgx.ErrNoRows) {
+		return notFound
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505":
+			return apperr.Wrap(err, apperr.CodeAlreadyExists, "resource already exists")
+		case "23503":
+			return apperr.Wrap(err, apperr.CodeInvalidReference, "referenced resource does not exist or is still in use")
+		case "23514", "22P02":
+			return apperr.Wrap(err, apperr.CodeValidation, "invalid value")
+		}
+	}
+	return err
+}
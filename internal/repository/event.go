@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"context"
+	"pr-reviewer/internal/models"
+)
+
+type EventRepository interface {
+	Create(ctx context.Context, event *models.PREvent) error
+	// CreateMany inserts events in a single query.
+	CreateMany(ctx context.Context, events []*models.PREvent) error
+	ListByPR(ctx context.Context, prID string) ([]models.PREvent, error)
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/store"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type eventRepoPG struct {
+	p *pgxpool.Pool
+}
+
+func NewEventRepositoryPG(p *pgxpool.Pool) EventRepository {
+	return &eventRepoPG{p: p}
+}
+
+// db runs queries inside the transaction carried by ctx, if any.
+func (r *eventRepoPG) db(ctx context.Context) store.DBTX {
+	return store.Conn(ctx, r.p)
+}
+
+func (r *eventRepoPG) Create(ctx context.Context, event *models.PREvent) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	query := `
+		INSERT INTO pr_events (pull_request_id, event_type, actor_id, details)
+		VALUES ($1, $2, $3, $4)
+		RETURNING event_id, created_at
+	`
+	// a nil RawMessage would be sent as the JSON literal null
+	var details any
+	if len(event.Details) > 0 {
+		details = event.Details
+	}
+	err := r.db(ctx).QueryRow(ctx, query, event.PullRequestID, event.Type, event.ActorID, details).
+		Scan(&event.EventID, &event.CreatedAt)
+	return translateError(err, nil)
+}
+
+func (r *eventRepoPG) CreateMany(ctx context.Context, events []*models.PREvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	prIDs := make([]string, 0, len(events))
+	types := make([]string, 0, len(events))
+	actors := make([]*string, 0, len(events))
+	details := make([]*string, 0, len(events))
+	for _, e := range events {
+		prIDs = append(prIDs, e.PullRequestID)
+		types = append(types, string(e.Type))
+		actors = append(actors, e.ActorID)
+		var d *string
+		if len(e.Details) > 0 {
+			raw := string(e.Details)
+			d = &raw
+		}
+		details = append(details, d)
+	}
+
+	query := `
+		INSERT INTO pr_events (pull_request_id, event_type, actor_id, details)
+		SELECT e.pull_request_id, e.event_type, e.actor_id, e.details::jsonb
+		FROM unnest($1::text[], $2::text[], $3::text[], $4::text[]) AS e(pull_request_id, event_type, actor_id, details)
+	`
+	_, err := r.db(ctx).Exec(ctx, query, prIDs, types, actors, details)
+	return translateError(err, nil)
+}
+
+func (r *eventRepoPG) ListByPR(ctx context.Context, prID string) ([]models.PREvent, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	query := `
+		SELECT event_id, pull_request_id, event_type, actor_id, details, created_at
+		FROM pr_events
+		WHERE pull_request_id = $1
+		ORDER BY created_at, event_id
+	`
+	rows, err := r.db(ctx).Query(ctx, query, prID)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+
+	list := make([]models.PREvent, 0)
+	for rows.Next() {
+		var e models.PREvent
+		if err := rows.Scan(&e.EventID, &e.PullRequestID, &e.Type, &e.ActorID, &e.Details, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, translateError(err, nil)
+	}
+
+	return list, nil
+}
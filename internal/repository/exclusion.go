@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+
+	"pr-reviewer/internal/apperr"
+	"pr-reviewer/internal/models"
+)
+
+var ErrExclusionNotFound = apperr.New(apperr.CodeNotFound, "exclusion not found")
+
+type ExclusionRepository interface {
+	Create(ctx context.Context, e *models.Exclusion) error
+	// ListByUser returns the exclusions the user is either side of.
+	ListByUser(ctx context.Context, userID string) ([]models.Exclusion, error)
+	// Delete removes the exclusion only when userID is either side of it.
+	Delete(ctx context.Context, userID string, id int64) error
+	// ExcludedReviewers returns the users who must not review pull requests of the author.
+	ExcludedReviewers(ctx context.Context, authorID string) (map[string]bool, error)
+}
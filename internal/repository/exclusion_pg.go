@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/store"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const exclusionColumns = `exclusion_id, author_id, reviewer_id, mutual, reason, created_at`
+
+type exclusionRepoPG struct {
+	p *pgxpool.Pool
+}
+
+func NewExclusionRepositoryPG(p *pgxpool.Pool) ExclusionRepository {
+	return &exclusionRepoPG{p: p}
+}
+
+// db runs queries inside the transaction carried by ctx, if any.
+func (r *exclusionRepoPG) db(ctx context.Context) store.DBTX {
+	return store.Conn(ctx, r.p)
+}
+
+func scanExclusion(row pgx.Row, e *models.Exclusion) error {
+	return row.Scan(&e.ExclusionID, &e.AuthorID, &e.ReviewerID, &e.Mutual, &e.Reason, &e.CreatedAt)
+}
+
+func (r *exclusionRepoPG) Create(ctx context.Context, e *models.Exclusion) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	query := `
+		INSERT INTO review_exclusions (author_id, reviewer_id, mutual, reason)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + exclusionColumns
+	err := scanExclusion(r.db(ctx).QueryRow(ctx, query, e.AuthorID, e.ReviewerID, e.Mutual, e.Reason), e)
+	return translateError(err, nil)
+}
+
+func (r *exclusionRepoPG) ListByUser(ctx context.Context, userID string) ([]models.Exclusion, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	query := `SELECT ` + exclusionColumns + ` FROM review_exclusions
+		WHERE author_id = $1 OR reviewer_id = $1
+		ORDER BY exclusion_id`
+	rows, err := r.db(ctx).Query(ctx, query, userID)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+	out := make([]models.Exclusion, 0)
+	for rows.Next() {
+		var e models.Exclusion
+		if err := scanExclusion(rows, &e); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, translateError(err, nil)
+	}
+	return out, nil
+}
+
+func (r *exclusionRepoPG) Delete(ctx context.Context, userID string, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tag, err := r.db(ctx).Exec(ctx, `
+		DELETE FROM review_exclusions
+		WHERE exclusion_id = $1 AND (author_id = $2 OR reviewer_id = $2)`, id, userID)
+	if err != nil {
+		return translateError(err, nil)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrExclusionNotFound
+	}
+	return nil
+}
+
+func (r *exclusionRepoPG) ExcludedReviewers(ctx context.Context, authorID string) (map[string]bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	rows, err := r.db(ctx).Query(ctx, `
+		SELECT reviewer_id FROM review_exclusions WHERE author_id = $1
+		UNION
+		SELECT author_id FROM review_exclusions WHERE reviewer_id = $1 AND mutual`, authorID)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+	out := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, translateError(err, nil)
+	}
+	return out, nil
+}
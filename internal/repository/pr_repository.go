@@ -2,22 +2,55 @@ package repository
 
 import (
 	"context"
-	"errors"
+	"pr-reviewer/internal/apperr"
 	"pr-reviewer/internal/models"
+	"time"
 )
 
 var (
-	ErrPRNotFound       = errors.New("pull request not found")
-	ErrReviewerNotFound = errors.New("reviewer not found")
-	ErrPRAlreadyMerged  = errors.New("pull request already merged")
+	ErrPRNotFound       = apperr.New(apperr.CodeNotFound, "pull request not found")
+	ErrReviewerNotFound = apperr.New(apperr.CodeNotAssigned, "reviewer is not assigned to this PR")
+	ErrPRAlreadyMerged  = apperr.New(apperr.CodePRMerged, "pull request already merged")
 )
 
 type PRRepository interface {
 	Create(ctx context.Context, pr *models.PullRequest) error
 	GetByID(ctx context.Context, id string) (*models.PullRequest, error)
+	// GetByIDForUpdate locks the PR row until the surrounding transaction ends.
+	GetByIDForUpdate(ctx context.Context, id string) (*models.PullRequest, error)
 	ListByReviewer(ctx context.Context, reviewerID string) ([]models.PullRequest, error)
 	SetMerged(ctx context.Context, id string) error
+	// SetStatus moves the PR to a non merged status. Transition rules are
+	// enforced by the service.
+	SetStatus(ctx context.Context, id string, status models.PRStatus) error
+	SetReviewerCount(ctx context.Context, id string, count int) error
 	AddReviewer(ctx context.Context, prID string, reviewerID string) error
 	RemoveReviewer(ctx context.Context, prID string, reviewerID string) error
 	ListReviewers(ctx context.Context, prID string) ([]models.User, error)
+	// LockOpenByReviewers locks the OPEN pull requests reviewed by any of the
+	// given users and returns them with AssignedReviewers filled. Rows are
+	// locked in pull_request_id order, like every other multi-PR lock. A
+	// non-empty teamName limits the result to the team's pull requests.
+	LockOpenByReviewers(ctx context.Context, reviewerIDs []string, teamName string) ([]models.PullRequest, error)
+	// ReplaceReviewers applies all reviewer swaps with a constant number of queries.
+	ReplaceReviewers(ctx context.Context, changes []models.ReviewerChange) error
+	// AddFiles records the paths changed by the pull request.
+	AddFiles(ctx context.Context, prID string, paths []string) error
+	ListFiles(ctx context.Context, prID string) ([]string, error)
+	// AddRequiredSkills records the areas the pull request needs a reviewer for.
+	AddRequiredSkills(ctx context.Context, prID string, skills []string) error
+	ListRequiredSkills(ctx context.Context, prID string) ([]string, error)
+	// ListPairings returns the assignment times of each of the reviewers to
+	// pull requests of the author since the given time, including
+	// assignments that were replaced or removed later.
+	ListPairings(ctx context.Context, authorID string, reviewerIDs []string, since time.Time) (map[string][]time.Time, error)
+	// CountPairings returns the author x reviewer counts of the team's pull
+	// requests created in [since, until).
+	CountPairings(ctx context.Context, teamName string, since, until time.Time) ([]models.Pairing, error)
+	// CountOpenReviews returns the number of OPEN pull requests each of the given users reviews.
+	CountOpenReviews(ctx context.Context, userIDs []string) (map[string]int, error)
+	// EnqueueReviewers records reviewer slots to fill once someone has capacity.
+	EnqueueReviewers(ctx context.Context, prID string, missing int) error
+	ListQueued(ctx context.Context) ([]models.QueuedReview, error)
+	Dequeue(ctx context.Context, prID string) error
 }
@@ -5,11 +5,14 @@ import (
 	"time"
 
 	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/store"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+const prColumns = `pull_request_id, pull_request_name, author_id, team_name, status, reviewer_count, created_at, merged_at, closed_at`
+
 type prRepoPG struct {
 	p *pgxpool.Pool
 }
@@ -18,46 +21,70 @@ func NewPRRepositoryPG(p *pgxpool.Pool) PRRepository {
 	return &prRepoPG{p: p}
 }
 
+// db runs queries inside the transaction carried by ctx, if any.
+func (r *prRepoPG) db(ctx context.Context) store.DBTX {
+	return store.Conn(ctx, r.p)
+}
+
+func scanPR(row pgx.Row, pr *models.PullRequest) error {
+	return row.Scan(
+		&pr.PullRequestID,
+		&pr.PullRequestName,
+		&pr.AuthorID,
+		&pr.TeamName,
+		&pr.Status,
+		&pr.ReviewerCount,
+		&pr.CreatedAt,
+		&pr.MergedAt,
+		&pr.ClosedAt,
+	)
+}
+
 func (r *prRepoPG) Create(ctx context.Context, pr *models.PullRequest) error {
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	query := `
-		INSERT INTO prs (pull_request_id, pull_request_name, author_id, team_name, status)
-		VALUES ($1, $2, $3, $4, 'OPEN')
+		INSERT INTO prs (pull_request_id, pull_request_name, author_id, team_name, status, reviewer_count)
+		VALUES ($1, $2, $3, $4, $5, $6)
 	`
-	_, err := r.p.Exec(ctx, query,
+	status := pr.Status
+	if status == "" {
+		status = models.PRStatusOpen
+	}
+	_, err := r.db(ctx).Exec(ctx, query,
 		pr.PullRequestID,
 		pr.PullRequestName,
 		pr.AuthorID,
 		pr.TeamName,
+		status,
+		pr.ReviewerCount,
 	)
-	return err
+	return translateError(err, nil)
 }
 
 func (r *prRepoPG) GetByID(ctx context.Context, id string) (*models.PullRequest, error) {
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
-	query := `
-		SELECT pull_request_id, pull_request_name, author_id, team_name, status, created_at, merged_at
-		FROM prs WHERE pull_request_id = $1
-	`
+	query := `SELECT ` + prColumns + ` FROM prs WHERE pull_request_id = $1`
 	var pr models.PullRequest
-	err := r.p.QueryRow(ctx, query, id).Scan(
-		&pr.PullRequestID,
-		&pr.PullRequestName,
-		&pr.AuthorID,
-		&pr.TeamName,
-		&pr.Status,
-		&pr.CreatedAt,
-		&pr.MergedAt,
-	)
-	if err == pgx.ErrNoRows {
-		return nil, ErrPRNotFound
+	if err := scanPR(r.db(ctx).QueryRow(ctx, query, id), &pr); err != nil {
+		return nil, translateError(err, ErrPRNotFound)
 	}
-	if err != nil {
-		return nil, err
+
+	return &pr, nil
+}
+
+func (r *prRepoPG) GetByIDForUpdate(ctx context.Context, id string) (*models.PullRequest, error) {
+	// waits for concurrent writers of the same PR, hence the longer timeout
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	query := `SELECT ` + prColumns + ` FROM prs WHERE pull_request_id = $1 FOR UPDATE`
+	var pr models.PullRequest
+	if err := scanPR(r.db(ctx).QueryRow(ctx, query, id), &pr); err != nil {
+		return nil, translateError(err, ErrPRNotFound)
 	}
 
 	return &pr, nil
@@ -68,37 +95,29 @@ func (r *prRepoPG) ListByReviewer(ctx context.Context, reviewerID string) ([]mod
 	defer cancel()
 
 	query := `
-		SELECT p.pull_request_id, p.pull_request_name, p.author_id, p.team_name, p.status, p.created_at, p.merged_at
+		SELECT p.pull_request_id, p.pull_request_name, p.author_id, p.team_name, p.status, p.reviewer_count,
+			p.created_at, p.merged_at, p.closed_at
 		FROM prs p
 		JOIN pr_reviewers r ON p.pull_request_id = r.pull_request_id
 		WHERE r.reviewer_id = $1
 	`
-	rows, err := r.p.Query(ctx, query, reviewerID)
+	rows, err := r.db(ctx).Query(ctx, query, reviewerID)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err, nil)
 	}
 	defer rows.Close()
 
 	var list []models.PullRequest
 	for rows.Next() {
 		var pr models.PullRequest
-		err := rows.Scan(
-			&pr.PullRequestID,
-			&pr.PullRequestName,
-			&pr.AuthorID,
-			&pr.TeamName,
-			&pr.Status,
-			&pr.CreatedAt,
-			&pr.MergedAt,
-		)
-		if err != nil {
+		if err := scanPR(rows, &pr); err != nil {
 			return nil, err
 		}
 		list = append(list, pr)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, translateError(err, nil)
 	}
 
 	return list, nil
@@ -112,9 +131,9 @@ func (r *prRepoPG) SetMerged(ctx context.Context, id string) error {
 		UPDATE prs SET status = 'MERGED', merged_at = NOW()
 		WHERE pull_request_id = $1 AND status != 'MERGED'
 	`
-	result, err := r.p.Exec(ctx, query, id)
+	result, err := r.db(ctx).Exec(ctx, query, id)
 	if err != nil {
-		return err
+		return translateError(err, nil)
 	}
 
 	rowsAffected := result.RowsAffected()
@@ -133,17 +152,56 @@ func (r *prRepoPG) SetMerged(ctx context.Context, id string) error {
 	return nil
 }
 
+func (r *prRepoPG) SetStatus(ctx context.Context, id string, status models.PRStatus) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	// closed_at tracks the latest close and is cleared on reopen
+	query := `
+		UPDATE prs SET status = $1,
+			closed_at = CASE WHEN $1 = 'CLOSED'::pr_status THEN NOW() ELSE NULL END
+		WHERE pull_request_id = $2
+	`
+	result, err := r.db(ctx).Exec(ctx, query, status, id)
+	if err != nil {
+		return translateError(err, nil)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrPRNotFound
+	}
+	return nil
+}
+
+func (r *prRepoPG) SetReviewerCount(ctx context.Context, id string, count int) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := r.db(ctx).Exec(ctx, `UPDATE prs SET reviewer_count = $1 WHERE pull_request_id = $2`, count, id)
+	if err != nil {
+		return translateError(err, nil)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrPRNotFound
+	}
+	return nil
+}
+
 func (r *prRepoPG) AddReviewer(ctx context.Context, prID string, reviewerID string) error {
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	query := `
-		INSERT INTO pr_reviewers (pull_request_id, reviewer_id)
-		VALUES ($1, $2)
-		ON CONFLICT (pull_request_id, reviewer_id) DO NOTHING
+		WITH added AS (
+			INSERT INTO pr_reviewers (pull_request_id, reviewer_id)
+			VALUES ($1, $2)
+			ON CONFLICT (pull_request_id, reviewer_id) DO NOTHING
+			RETURNING pull_request_id, reviewer_id
+		)
+		INSERT INTO reviewer_assignments (pull_request_id, reviewer_id)
+		SELECT pull_request_id, reviewer_id FROM added
 	`
-	_, err := r.p.Exec(ctx, query, prID, reviewerID)
-	return err
+	_, err := r.db(ctx).Exec(ctx, query, prID, reviewerID)
+	return translateError(err, nil)
 }
 
 func (r *prRepoPG) RemoveReviewer(ctx context.Context, prID string, reviewerID string) error {
@@ -154,9 +212,9 @@ func (r *prRepoPG) RemoveReviewer(ctx context.Context, prID string, reviewerID s
 		DELETE FROM pr_reviewers
 		WHERE pull_request_id = $1 AND reviewer_id = $2
 	`
-	result, err := r.p.Exec(ctx, query, prID, reviewerID)
+	result, err := r.db(ctx).Exec(ctx, query, prID, reviewerID)
 	if err != nil {
-		return err
+		return translateError(err, nil)
 	}
 
 	rowsAffected := result.RowsAffected()
@@ -172,37 +230,353 @@ func (r *prRepoPG) ListReviewers(ctx context.Context, prID string) ([]models.Use
 	defer cancel()
 
 	query := `
-		SELECT u.user_id, u.username, u.display_name, u.is_active, u.team_name, u.created_at
-		FROM pr_reviewers r 
-		JOIN users u ON r.reviewer_id = u.user_id
+		SELECT ` + userColumns + `
+		FROM pr_reviewers r
+		JOIN users ON r.reviewer_id = users.user_id
 		WHERE r.pull_request_id = $1
 	`
-	rows, err := r.p.Query(ctx, query, prID)
+	rows, err := r.db(ctx).Query(ctx, query, prID)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err, nil)
 	}
 	defer rows.Close()
 
 	var result []models.User
 	for rows.Next() {
 		var u models.User
+		if err := scanUser(rows, &u); err != nil {
+			return nil, err
+		}
+		result = append(result, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, translateError(err, nil)
+	}
+
+	return result, nil
+}
+
+func (r *prRepoPG) LockOpenByReviewers(ctx context.Context, reviewerIDs []string, teamName string) ([]models.PullRequest, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	query := `
+		SELECT ` + prColumns + `,
+			ARRAY(SELECT x.reviewer_id FROM pr_reviewers x WHERE x.pull_request_id = p.pull_request_id ORDER BY x.reviewer_id)
+		FROM prs p
+		WHERE p.status = 'OPEN' AND ($2 = '' OR p.team_name = $2) AND EXISTS (
+			SELECT 1 FROM pr_reviewers r
+			WHERE r.pull_request_id = p.pull_request_id AND r.reviewer_id = ANY($1)
+		)
+		ORDER BY p.pull_request_id
+		FOR UPDATE OF p
+	`
+	rows, err := r.db(ctx).Query(ctx, query, reviewerIDs, teamName)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+
+	list := make([]models.PullRequest, 0)
+	for rows.Next() {
+		var pr models.PullRequest
 		err := rows.Scan(
-			&u.UserID,
-			&u.Username,
-			&u.DisplayName,
-			&u.IsActive,
-			&u.TeamName,
-			&u.CreatedAt,
+			&pr.PullRequestID,
+			&pr.PullRequestName,
+			&pr.AuthorID,
+			&pr.TeamName,
+			&pr.Status,
+			&pr.ReviewerCount,
+			&pr.CreatedAt,
+			&pr.MergedAt,
+			&pr.ClosedAt,
+			&pr.AssignedReviewers,
 		)
 		if err != nil {
 			return nil, err
 		}
-		result = append(result, u)
+		list = append(list, pr)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, translateError(err, nil)
+	}
+
+	return list, nil
+}
+
+func (r *prRepoPG) ReplaceReviewers(ctx context.Context, changes []models.ReviewerChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	prIDs := make([]string, 0, len(changes))
+	oldIDs := make([]string, 0, len(changes))
+	newIDs := make([]string, 0, len(changes))
+	for _, c := range changes {
+		prIDs = append(prIDs, c.PullRequestID)
+		oldIDs = append(oldIDs, c.OldReviewerID)
+		newIDs = append(newIDs, c.NewReviewerID)
+	}
+
+	_, err := r.db(ctx).Exec(ctx, `
+		DELETE FROM pr_reviewers r
+		USING unnest($1::text[], $2::text[]) AS c(pull_request_id, reviewer_id)
+		WHERE r.pull_request_id = c.pull_request_id AND r.reviewer_id = c.reviewer_id
+	`, prIDs, oldIDs)
+	if err != nil {
+		return translateError(err, nil)
+	}
+
+	_, err = r.db(ctx).Exec(ctx, `
+		WITH added AS (
+			INSERT INTO pr_reviewers (pull_request_id, reviewer_id)
+			SELECT * FROM unnest($1::text[], $2::text[])
+			ON CONFLICT (pull_request_id, reviewer_id) DO NOTHING
+			RETURNING pull_request_id, reviewer_id
+		)
+		INSERT INTO reviewer_assignments (pull_request_id, reviewer_id)
+		SELECT pull_request_id, reviewer_id FROM added
+	`, prIDs, newIDs)
+	return translateError(err, nil)
+}
+
+func (r *prRepoPG) CountOpenReviews(ctx context.Context, userIDs []string) (map[string]int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	query := `
+		SELECT r.reviewer_id, COUNT(*)
+		FROM pr_reviewers r
+		JOIN prs p ON p.pull_request_id = r.pull_request_id
+		WHERE p.status = 'OPEN' AND r.reviewer_id = ANY($1)
+		GROUP BY r.reviewer_id
+	`
+	rows, err := r.db(ctx).Query(ctx, query, userIDs)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+
+	result := make(map[string]int, len(userIDs))
+	for rows.Next() {
+		var id string
+		var count int
+		if err := rows.Scan(&id, &count); err != nil {
+			return nil, err
+		}
+		result[id] = count
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, translateError(err, nil)
 	}
 
 	return result, nil
 }
+
+func (r *prRepoPG) EnqueueReviewers(ctx context.Context, prID string, missing int) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	query := `
+		INSERT INTO pr_review_queue (pull_request_id, missing_reviewers)
+		VALUES ($1, $2)
+		ON CONFLICT (pull_request_id) DO UPDATE SET missing_reviewers = EXCLUDED.missing_reviewers
+	`
+	_, err := r.db(ctx).Exec(ctx, query, prID, missing)
+	return translateError(err, nil)
+}
+
+func (r *prRepoPG) ListQueued(ctx context.Context) ([]models.QueuedReview, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	query := `
+		SELECT q.pull_request_id, q.missing_reviewers, q.queued_at
+		FROM pr_review_queue q
+		JOIN prs p ON p.pull_request_id = q.pull_request_id
+		WHERE p.status = 'OPEN'
+		ORDER BY q.queued_at, q.pull_request_id
+	`
+	rows, err := r.db(ctx).Query(ctx, query)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+
+	var list []models.QueuedReview
+	for rows.Next() {
+		var q models.QueuedReview
+		if err := rows.Scan(&q.PullRequestID, &q.MissingReviewers, &q.QueuedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, q)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, translateError(err, nil)
+	}
+
+	return list, nil
+}
+
+func (r *prRepoPG) Dequeue(ctx context.Context, prID string) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err := r.db(ctx).Exec(ctx, `DELETE FROM pr_review_queue WHERE pull_request_id = $1`, prID)
+	return translateError(err, nil)
+}
+
+func (r *prRepoPG) AddFiles(ctx context.Context, prID string, paths []string) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	query := `
+		INSERT INTO pr_files (pull_request_id, path)
+		SELECT $1, p FROM unnest($2::text[]) AS p
+		ON CONFLICT DO NOTHING
+	`
+	_, err := r.db(ctx).Exec(ctx, query, prID, paths)
+	return translateError(err, nil)
+}
+
+func (r *prRepoPG) ListFiles(ctx context.Context, prID string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	query := `SELECT path FROM pr_files WHERE pull_request_id = $1 ORDER BY path`
+	rows, err := r.db(ctx).Query(ctx, query, prID)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+
+	out := make([]string, 0)
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		out = append(out, path)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, translateError(err, nil)
+	}
+
+	return out, nil
+}
+
+func (r *prRepoPG) AddRequiredSkills(ctx context.Context, prID string, skills []string) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	query := `
+		INSERT INTO pr_skills (pull_request_id, skill)
+		SELECT $1, s FROM unnest($2::text[]) AS s
+		ON CONFLICT DO NOTHING
+	`
+	_, err := r.db(ctx).Exec(ctx, query, prID, skills)
+	return translateError(err, nil)
+}
+
+func (r *prRepoPG) ListRequiredSkills(ctx context.Context, prID string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	query := `SELECT skill FROM pr_skills WHERE pull_request_id = $1 ORDER BY skill`
+	rows, err := r.db(ctx).Query(ctx, query, prID)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+
+	out := make([]string, 0)
+	for rows.Next() {
+		var skill string
+		if err := rows.Scan(&skill); err != nil {
+			return nil, err
+		}
+		out = append(out, skill)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, translateError(err, nil)
+	}
+
+	return out, nil
+}
+
+func (r *prRepoPG) ListPairings(ctx context.Context, authorID string, reviewerIDs []string, since time.Time) (map[string][]time.Time, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	// reviewers replaced or removed since count as well, once per PR
+	query := `
+		SELECT a.reviewer_id, MIN(a.assigned_at)
+		FROM reviewer_assignments a
+		JOIN prs p ON p.pull_request_id = a.pull_request_id
+		WHERE p.author_id = $1 AND a.reviewer_id = ANY($2) AND a.assigned_at >= $3
+		GROUP BY a.pull_request_id, a.reviewer_id
+	`
+	rows, err := r.db(ctx).Query(ctx, query, authorID, reviewerIDs, since)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+
+	res := make(map[string][]time.Time, len(reviewerIDs))
+	for rows.Next() {
+		var id string
+		var at time.Time
+		if err := rows.Scan(&id, &at); err != nil {
+			return nil, err
+		}
+		res[id] = append(res[id], at)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, translateError(err, nil)
+	}
+
+	return res, nil
+}
+
+func (r *prRepoPG) CountPairings(ctx context.Context, teamName string, since, until time.Time) ([]models.Pairing, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	query := `
+		SELECT p.author_id, a.reviewer_id, COUNT(DISTINCT a.pull_request_id)
+		FROM prs p
+		JOIN reviewer_assignments a ON a.pull_request_id = p.pull_request_id
+		WHERE p.team_name = $1 AND p.created_at >= $2 AND p.created_at < $3
+		GROUP BY p.author_id, a.reviewer_id
+		ORDER BY p.author_id, a.reviewer_id
+	`
+	rows, err := r.db(ctx).Query(ctx, query, teamName, since, until)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+
+	res := make([]models.Pairing, 0)
+	for rows.Next() {
+		var p models.Pairing
+		if err := rows.Scan(&p.AuthorID, &p.ReviewerID, &p.Count); err != nil {
+			return nil, err
+		}
+		res = append(res, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, translateError(err, nil)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"context"
+	"pr-reviewer/internal/models"
+)
+
+type ReviewRepository interface {
+	Create(ctx context.Context, review *models.Review) error
+	ListByPR(ctx context.Context, prID string) ([]models.Review, error)
+	// LatestByReviewer returns the reviewer's most recent review per pull request.
+	LatestByReviewer(ctx context.Context, reviewerID string) (map[string]models.Review, error)
+}
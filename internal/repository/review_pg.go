@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/store"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const reviewColumns = `review_id, pull_request_id, reviewer_id, verdict, message, created_at`
+
+type reviewRepoPG struct {
+	p *pgxpool.Pool
+}
+
+func NewReviewRepositoryPG(p *pgxpool.Pool) ReviewRepository {
+	return &reviewRepoPG{p: p}
+}
+
+// db runs queries inside the transaction carried by ctx, if any.
+func (r *reviewRepoPG) db(ctx context.Context) store.DBTX {
+	return store.Conn(ctx, r.p)
+}
+
+func scanReview(row pgx.Row, rv *models.Review) error {
+	return row.Scan(&rv.ReviewID, &rv.PullRequestID, &rv.ReviewerID, &rv.Verdict, &rv.Message, &rv.CreatedAt)
+}
+
+func (r *reviewRepoPG) Create(ctx context.Context, review *models.Review) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	query := `
+		INSERT INTO pr_reviews (pull_request_id, reviewer_id, verdict, message)
+		VALUES ($1, $2, $3, $4)
+		RETURNING review_id, created_at
+	`
+	err := r.db(ctx).QueryRow(ctx, query, review.PullRequestID, review.ReviewerID, review.Verdict, review.Message).
+		Scan(&review.ReviewID, &review.CreatedAt)
+	return translateError(err, nil)
+}
+
+func (r *reviewRepoPG) ListByPR(ctx context.Context, prID string) ([]models.Review, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	query := `SELECT ` + reviewColumns + ` FROM pr_reviews WHERE pull_request_id = $1 ORDER BY created_at, review_id`
+	rows, err := r.db(ctx).Query(ctx, query, prID)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+
+	list := make([]models.Review, 0)
+	for rows.Next() {
+		var rv models.Review
+		if err := scanReview(rows, &rv); err != nil {
+			return nil, err
+		}
+		list = append(list, rv)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, translateError(err, nil)
+	}
+
+	return list, nil
+}
+
+func (r *reviewRepoPG) LatestByReviewer(ctx context.Context, reviewerID string) (map[string]models.Review, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	query := `
+		SELECT DISTINCT ON (pull_request_id) ` + reviewColumns + `
+		FROM pr_reviews
+		WHERE reviewer_id = $1
+		ORDER BY pull_request_id, created_at DESC, review_id DESC
+	`
+	rows, err := r.db(ctx).Query(ctx, query, reviewerID)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+
+	result := make(map[string]models.Review)
+	for rows.Next() {
+		var rv models.Review
+		if err := scanReview(rows, &rv); err != nil {
+			return nil, err
+		}
+		result[rv.PullRequestID] = rv
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, translateError(err, nil)
+	}
+
+	return result, nil
+}
@@ -2,12 +2,35 @@ package repository
 
 import (
 	"context"
+	"pr-reviewer/internal/apperr"
 	"pr-reviewer/internal/models"
 )
 
+var (
+	ErrTeamNotFound       = apperr.New(apperr.CodeNotFound, "team not found")
+	ErrCodeOwnersNotFound = apperr.New(apperr.CodeNotFound, "team has no CODEOWNERS file")
+)
+
 type TeamRepository interface {
 	Create(ctx context.Context, teamName string, description *string) (*models.Team, error)
 	GetByName(ctx context.Context, name string) (*models.Team, error)
 	List(ctx context.Context) ([]models.Team, error)
+	UpdateSettings(ctx context.Context, name string, settings models.TeamSettings) error
+	// SetParent moves the team under parent; nil makes it a root team.
+	SetParent(ctx context.Context, name string, parent *string) error
+	// ListAncestors returns the team followed by its parent, grandparent and so on.
+	ListAncestors(ctx context.Context, name string) ([]models.Team, error)
 	Delete(ctx context.Context, name string) error
+	// ListFallbacks returns the fallback teams in priority order.
+	ListFallbacks(ctx context.Context, name string) ([]string, error)
+	// SetFallbacks replaces the fallback teams; their order is the priority.
+	SetFallbacks(ctx context.Context, name string, fallbacks []string) error
+	GetCodeOwners(ctx context.Context, name string) (string, error)
+	// SetCodeOwners stores the CODEOWNERS file of the team, replacing the previous one.
+	SetCodeOwners(ctx context.Context, name string, content string) error
+	// LockLastAssigned returns the last reviewer picked by round robin in the
+	// team's rotation, empty when there is none, and locks it until the
+	// transaction ends.
+	LockLastAssigned(ctx context.Context, name string, rotation string) (string, error)
+	SetLastAssigned(ctx context.Context, name string, rotation string, userID string) error
 }
@@ -2,15 +2,18 @@ package repository
 
 import (
 	"context"
-	"errors"
 	"time"
 
 	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/store"
 
-	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+const teamColumns = `team_name, description, parent_team, reviewer_strategy, min_approvals, block_on_changes_requested, forbid_self_approval, on_reviewer_deactivation,
+	default_reviewers, min_reviewers, max_reviewers, min_senior_reviewers, junior_needs_lead, created_at`
+
 type teamRepoPG struct {
 	p *pgxpool.Pool
 }
@@ -19,22 +22,47 @@ func NewTeamRepositoryPG(p *pgxpool.Pool) TeamRepository {
 	return &teamRepoPG{p: p}
 }
 
+// db runs queries inside the transaction carried by ctx, if any.
+func (r *teamRepoPG) db(ctx context.Context) store.DBTX {
+	return store.Conn(ctx, r.p)
+}
+
+func scanTeam(row pgx.Row, t *models.Team) error {
+	return row.Scan(
+		&t.TeamName,
+		&t.Desc,
+		&t.ParentTeam,
+		&t.ReviewerStrategy,
+		&t.MinApprovals,
+		&t.BlockOnChangesRequested,
+		&t.ForbidSelfApproval,
+		&t.OnReviewerDeactivation,
+		&t.DefaultReviewers,
+		&t.MinReviewers,
+		&t.MaxReviewers,
+		&t.MinSeniorReviewers,
+		&t.JuniorNeedsLead,
+		&t.CreatedAt,
+	)
+}
+
 func (r *teamRepoPG) Create(ctx context.Context, teamName string, description *string) (*models.Team, error) {
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 	var t models.Team
-	err := r.p.QueryRow(ctx, `INSERT INTO teams(team_name, description) VALUES ($1,$2) RETURNING team_name, description, created_at`, teamName, description).
-		Scan(&t.TeamName, &t.Desc, &t.CreatedAt)
-	return &t, err
+	row := r.db(ctx).QueryRow(ctx, `INSERT INTO teams(team_name, description) VALUES ($1,$2) RETURNING `+teamColumns, teamName, description)
+	if err := scanTeam(row, &t); err != nil {
+		return nil, translateError(err, nil)
+	}
+	return &t, nil
 }
 
 func (r *teamRepoPG) GetByName(ctx context.Context, name string) (*models.Team, error) {
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 	var t models.Team
-	if err := r.p.QueryRow(ctx, `SELECT team_name, description, created_at FROM teams WHERE team_name = $1`, name).
-		Scan(&t.TeamName, &t.Desc, &t.CreatedAt); err != nil {
-		return nil, err
+	if err := scanTeam(r.db(ctx).QueryRow(ctx, `SELECT `+teamColumns+` FROM teams WHERE team_name = $1`, name), &t); err != nil {
+		return nil, translateError(err, ErrTeamNotFound)
 	}
 	return &t, nil
 }
@@ -42,36 +70,191 @@ func (r *teamRepoPG) GetByName(ctx context.Context, name string) (*models.Team,
 func (r *teamRepoPG) List(ctx context.Context) ([]models.Team, error) {
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
-	rows, err := r.p.Query(ctx, `SELECT team_name, description, created_at FROM teams ORDER BY team_name`)
+	rows, err := r.db(ctx).Query(ctx, `SELECT `+teamColumns+` FROM teams ORDER BY team_name`)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err, nil)
 	}
 	defer rows.Close()
 	var out []models.Team
 	for rows.Next() {
 		var t models.Team
-		if err := rows.Scan(&t.TeamName, &t.Desc, &t.CreatedAt); err != nil {
+		if err := scanTeam(rows, &t); err != nil {
 			return nil, err
 		}
 		out = append(out, t)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, translateError(err, nil)
+	}
 	return out, nil
 }
 
-var ErrForeignKeyViolation = errors.New("foreign key violation")
+func (r *teamRepoPG) UpdateSettings(ctx context.Context, name string, settings models.TeamSettings) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	_, err := r.db(ctx).Exec(ctx, `UPDATE teams SET
+		reviewer_strategy = CASE WHEN $1::text IS NULL THEN reviewer_strategy ELSE NULLIF($1::text, '') END,
+		min_approvals = COALESCE($2, min_approvals),
+		block_on_changes_requested = COALESCE($3, block_on_changes_requested),
+		forbid_self_approval = COALESCE($4, forbid_self_approval),
+		on_reviewer_deactivation = COALESCE($5, on_reviewer_deactivation),
+		default_reviewers = COALESCE($6, default_reviewers),
+		min_reviewers = COALESCE($7, min_reviewers),
+		max_reviewers = COALESCE($8, max_reviewers),
+		min_senior_reviewers = COALESCE($9, min_senior_reviewers),
+		junior_needs_lead = COALESCE($10, junior_needs_lead)
+		WHERE team_name = $11`,
+		settings.ReviewerStrategy, settings.MinApprovals, settings.BlockOnChangesRequested, settings.ForbidSelfApproval,
+		settings.OnReviewerDeactivation, settings.DefaultReviewers, settings.MinReviewers, settings.MaxReviewers,
+		settings.MinSeniorReviewers, settings.JuniorNeedsLead, name)
+	return translateError(err, nil)
+}
+
+func (r *teamRepoPG) SetParent(ctx context.Context, name string, parent *string) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	tag, err := r.db(ctx).Exec(ctx, `UPDATE teams SET parent_team = $1 WHERE team_name = $2`, parent, name)
+	if err != nil {
+		return translateError(err, nil)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTeamNotFound
+	}
+	return nil
+}
+
+func (r *teamRepoPG) ListAncestors(ctx context.Context, name string) ([]models.Team, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	// depth bound guards against cycles written around the service
+	rows, err := r.db(ctx).Query(ctx, `
+		WITH RECURSIVE chain(team_name, depth) AS (
+			SELECT team_name, 0 FROM teams WHERE team_name = $1
+			UNION ALL
+			SELECT t.parent_team, c.depth + 1
+			FROM chain c JOIN teams t ON t.team_name = c.team_name
+			WHERE t.parent_team IS NOT NULL AND c.depth < 32
+		)
+		SELECT `+teamColumns+` FROM teams JOIN chain USING (team_name) ORDER BY chain.depth`, name)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+	var out []models.Team
+	for rows.Next() {
+		var t models.Team
+		if err := scanTeam(rows, &t); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, translateError(err, nil)
+	}
+	if len(out) == 0 {
+		return nil, ErrTeamNotFound
+	}
+	return out, nil
+}
 
 func (r *teamRepoPG) Delete(ctx context.Context, name string) error {
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
-	_, err := r.p.Exec(ctx, `DELETE FROM teams WHERE team_name = $1`, name)
+	tag, err := r.db(ctx).Exec(ctx, `DELETE FROM teams WHERE team_name = $1`, name)
+	if err != nil {
+		return translateError(err, nil)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTeamNotFound
+	}
+	return nil
+}
+
+func (r *teamRepoPG) ListFallbacks(ctx context.Context, name string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	rows, err := r.db(ctx).Query(ctx,
+		`SELECT fallback_team FROM team_fallbacks WHERE team_name = $1 ORDER BY priority, fallback_team`, name)
 	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) {
-			if pgErr.Code == "23503" {
-				return ErrForeignKeyViolation
-			}
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+	out := make([]string, 0)
+	for rows.Next() {
+		var fb string
+		if err := rows.Scan(&fb); err != nil {
+			return nil, err
 		}
-		return err
+		out = append(out, fb)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, translateError(err, nil)
+	}
+	return out, nil
+}
+
+func (r *teamRepoPG) SetFallbacks(ctx context.Context, name string, fallbacks []string) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	if _, err := r.db(ctx).Exec(ctx, `DELETE FROM team_fallbacks WHERE team_name = $1`, name); err != nil {
+		return translateError(err, nil)
+	}
+	_, err := r.db(ctx).Exec(ctx, `
+		INSERT INTO team_fallbacks (team_name, fallback_team, priority)
+		SELECT $1, f.team, f.priority FROM unnest($2::text[]) WITH ORDINALITY AS f(team, priority)`,
+		name, fallbacks)
+	return translateError(err, nil)
+}
+
+func (r *teamRepoPG) GetCodeOwners(ctx context.Context, name string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	var content string
+	err := r.db(ctx).QueryRow(ctx, `SELECT content FROM team_codeowners WHERE team_name = $1`, name).Scan(&content)
+	if err != nil {
+		return "", translateError(err, ErrCodeOwnersNotFound)
+	}
+	return content, nil
+}
+
+func (r *teamRepoPG) SetCodeOwners(ctx context.Context, name string, content string) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	_, err := r.db(ctx).Exec(ctx, `
+		INSERT INTO team_codeowners (team_name, content) VALUES ($1, $2)
+		ON CONFLICT (team_name) DO UPDATE SET content = EXCLUDED.content, updated_at = now()`,
+		name, content)
+	return translateError(err, nil)
+}
+
+func (r *teamRepoPG) LockLastAssigned(ctx context.Context, name string, rotation string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	// the no-op update creates a missing cursor and locks it either way
+	var last *string
+	err := r.db(ctx).QueryRow(ctx, `
+		INSERT INTO round_robin_cursors (team_name, rotation) VALUES ($1, $2)
+		ON CONFLICT (team_name, rotation) DO UPDATE SET rotation = EXCLUDED.rotation
+		RETURNING last_assigned`, name, rotation).Scan(&last)
+	if err != nil {
+		return "", translateError(err, nil)
+	}
+	if last == nil {
+		return "", nil
+	}
+	return *last, nil
+}
+
+func (r *teamRepoPG) SetLastAssigned(ctx context.Context, name string, rotation string, userID string) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	tag, err := r.db(ctx).Exec(ctx, `UPDATE round_robin_cursors SET last_assigned = $3 WHERE team_name = $1 AND rotation = $2`,
+		name, rotation, userID)
+	if err != nil {
+		return translateError(err, nil)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTeamNotFound
 	}
 	return nil
 }
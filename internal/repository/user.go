@@ -2,14 +2,46 @@ package repository
 
 import (
 	"context"
+	"pr-reviewer/internal/apperr"
 	"pr-reviewer/internal/models"
 )
 
+var (
+	ErrUserNotFound       = apperr.New(apperr.CodeNotFound, "user not found")
+	ErrMembershipNotFound = apperr.New(apperr.CodeNotFound, "user is not a member of the team")
+)
+
 type UserRepository interface {
-	Create(ctx context.Context, username string, displayName *string, teamName *string) (*models.User, error)
+	Create(ctx context.Context, username string, displayName *string) (*models.User, error)
+	// Upsert creates the user with the given id or updates the existing one.
+	Upsert(ctx context.Context, id string, username string, isActive bool) (*models.User, error)
 	GetByID(ctx context.Context, id string) (*models.User, error)
 	List(ctx context.Context) ([]models.User, error)
+	// ListByHandles returns the users whose user_id or username is among handles.
+	ListByHandles(ctx context.Context, handles []string) ([]models.User, error)
+	// ListUsersByTeam returns the members of the team. A member is active
+	// only when both the user and the membership are.
 	ListUsersByTeam(ctx context.Context, teamName string) ([]models.User, error)
-	Update(ctx context.Context, id string, displayName *string, isActive *bool, teamName *string) error
+	ListMembers(ctx context.Context, teamName string) ([]models.TeamMember, error)
+	ListMemberships(ctx context.Context, userID string) ([]models.Membership, error)
+	// LevelsOf returns the seniority of each user in the team, or their highest
+	// level in any team when they are not a member of it.
+	LevelsOf(ctx context.Context, teamName string, userIDs []string) (map[string]string, error)
+	// AddMembership creates the membership or updates its flag, role and
+	// level. An empty Role or Level keeps the stored one, or is member and
+	// middle for a new membership; m gets the resulting values.
+	AddMembership(ctx context.Context, m *models.Membership) error
+	RemoveMembership(ctx context.Context, teamName string, userID string) error
+	// SetMembershipsActive updates is_active of every membership of the team
+	// and returns the ids of its members.
+	SetMembershipsActive(ctx context.Context, teamName string, active bool) ([]string, error)
+	Update(ctx context.Context, id string, upd models.UserUpdate) error
+	// SetSkills replaces the skills of the user.
+	SetSkills(ctx context.Context, id string, skills []string) error
+	// SetActive updates is_active of all given users at once and returns the ids found.
+	SetActive(ctx context.Context, ids []string, active bool) ([]string, error)
+	// LockCapacity locks the rows of the given users that have a
+	// max_open_reviews limit until the transaction ends.
+	LockCapacity(ctx context.Context, ids []string) error
 	Delete(ctx context.Context, id string) error
 }
@@ -5,10 +5,18 @@ import (
 	"time"
 
 	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/store"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+const userColumns = `users.user_id, users.username, users.display_name, users.is_active,
+	(SELECT m.team_name FROM team_members m WHERE m.user_id = users.user_id ORDER BY m.joined_at, m.team_name LIMIT 1),
+	ARRAY(SELECT m.team_name FROM team_members m WHERE m.user_id = users.user_id ORDER BY m.team_name),
+	ARRAY(SELECT s.skill FROM user_skills s WHERE s.user_id = users.user_id ORDER BY s.skill),
+	users.max_open_reviews, users.is_admin, users.calendar_path, users.timezone, users.work_start, users.work_end, users.created_at`
+
 type userRepoPG struct {
 	p *pgxpool.Pool
 }
@@ -17,15 +25,57 @@ func NewUserRepositoryPG(p *pgxpool.Pool) UserRepository {
 	return &userRepoPG{p: p}
 }
 
-func (r *userRepoPG) Create(ctx context.Context, username string, displayName *string, teamName *string) (*models.User, error) {
+// db runs queries inside the transaction carried by ctx, if any.
+func (r *userRepoPG) db(ctx context.Context) store.DBTX {
+	return store.Conn(ctx, r.p)
+}
+
+// userFields lists the scan targets matching userColumns.
+func userFields(u *models.User) []any {
+	return []any{
+		&u.UserID,
+		&u.Username,
+		&u.DisplayName,
+		&u.IsActive,
+		&u.TeamName,
+		&u.Teams,
+		&u.Skills,
+		&u.MaxOpenReviews,
+		&u.IsAdmin,
+		&u.CalendarPath,
+		&u.Timezone,
+		&u.WorkStart,
+		&u.WorkEnd,
+		&u.CreatedAt,
+	}
+}
+
+func scanUser(row pgx.Row, u *models.User) error {
+	return row.Scan(userFields(u)...)
+}
+
+func (r *userRepoPG) Create(ctx context.Context, username string, displayName *string) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	query := `INSERT INTO users(username, display_name) VALUES ($1,$2)
+	          RETURNING ` + userColumns
+	var u models.User
+	if err := scanUser(r.db(ctx).QueryRow(ctx, query, username, displayName), &u); err != nil {
+		return nil, translateError(err, nil)
+	}
+	return &u, nil
+}
+
+func (r *userRepoPG) Upsert(ctx context.Context, id string, username string, isActive bool) (*models.User, error) {
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
-	query := `INSERT INTO users(username, display_name, team_name) VALUES ($1,$2,$3)
-	          RETURNING user_id, username, display_name, is_active, team_name, created_at`
+	query := `INSERT INTO users(user_id, username, display_name, is_active) VALUES ($1,$2,$2,$3)
+	          ON CONFLICT (user_id) DO UPDATE SET username = EXCLUDED.username, display_name = EXCLUDED.display_name,
+	          is_active = EXCLUDED.is_active
+	          RETURNING ` + userColumns
 	var u models.User
-	row := r.p.QueryRow(ctx, query, username, displayName, teamName)
-	if err := row.Scan(&u.UserID, &u.Username, &u.DisplayName, &u.IsActive, &u.TeamName, &u.CreatedAt); err != nil {
-		return nil, err
+	if err := scanUser(r.db(ctx).QueryRow(ctx, query, id, username, isActive), &u); err != nil {
+		return nil, translateError(err, nil)
 	}
 	return &u, nil
 }
@@ -34,9 +84,9 @@ func (r *userRepoPG) GetByID(ctx context.Context, id string) (*models.User, erro
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 	var u models.User
-	row := r.p.QueryRow(ctx, `SELECT user_id, username, display_name, is_active, team_name, created_at FROM users WHERE user_id = $1`, id)
-	if err := row.Scan(&u.UserID, &u.Username, &u.DisplayName, &u.IsActive, &u.TeamName, &u.CreatedAt); err != nil {
-		return nil, err
+	err := scanUser(r.db(ctx).QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE user_id = $1`, id), &u)
+	if err != nil {
+		return nil, translateError(err, ErrUserNotFound)
 	}
 	return &u, nil
 }
@@ -44,51 +94,276 @@ func (r *userRepoPG) GetByID(ctx context.Context, id string) (*models.User, erro
 func (r *userRepoPG) List(ctx context.Context) ([]models.User, error) {
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
-	rows, err := r.p.Query(ctx, `SELECT user_id, username, display_name, is_active, team_name, created_at FROM users`)
+	rows, err := r.db(ctx).Query(ctx, `SELECT `+userColumns+` FROM users`)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err, nil)
 	}
 	defer rows.Close()
 	res := make([]models.User, 0)
 	for rows.Next() {
 		var u models.User
-		if err := rows.Scan(&u.UserID, &u.Username, &u.DisplayName, &u.IsActive, &u.TeamName, &u.CreatedAt); err != nil {
+		if err := scanUser(rows, &u); err != nil {
 			return nil, err
 		}
 		res = append(res, u)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, translateError(err, nil)
+	}
+	return res, nil
+}
+
+func (r *userRepoPG) ListByHandles(ctx context.Context, handles []string) ([]models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	rows, err := r.db(ctx).Query(ctx, `SELECT `+userColumns+` FROM users
+		WHERE users.user_id = ANY($1) OR users.username = ANY($1) ORDER BY users.user_id`, handles)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+	res := make([]models.User, 0, len(handles))
+	for rows.Next() {
+		var u models.User
+		if err := scanUser(rows, &u); err != nil {
+			return nil, err
+		}
+		res = append(res, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, translateError(err, nil)
+	}
 	return res, nil
 }
 
 func (r *userRepoPG) ListUsersByTeam(ctx context.Context, teamName string) ([]models.User, error) {
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
-	rows, err := r.p.Query(ctx, `SELECT user_id, username, display_name, is_active, team_name, created_at FROM users WHERE team_name = $1`, teamName)
+	rows, err := r.db(ctx).Query(ctx, `SELECT `+userColumns+`, tm.is_active, tm.level
+		FROM users JOIN team_members tm ON tm.user_id = users.user_id
+		WHERE tm.team_name = $1 ORDER BY users.user_id`, teamName)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err, nil)
 	}
 	defer rows.Close()
 	res := make([]models.User, 0)
 	for rows.Next() {
 		var u models.User
-		if err := rows.Scan(&u.UserID, &u.Username, &u.DisplayName, &u.IsActive, &u.TeamName, &u.CreatedAt); err != nil {
+		var memberActive bool
+		if err := rows.Scan(append(userFields(&u), &memberActive, &u.Level)...); err != nil {
 			return nil, err
 		}
+		u.IsActive = u.IsActive && memberActive
 		res = append(res, u)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, translateError(err, nil)
+	}
+	return res, nil
+}
+
+func (r *userRepoPG) ListMembers(ctx context.Context, teamName string) ([]models.TeamMember, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	rows, err := r.db(ctx).Query(ctx, `
+		SELECT u.user_id, u.username, u.is_active AND tm.is_active, tm.role, tm.level
+		FROM team_members tm JOIN users u ON u.user_id = tm.user_id
+		WHERE tm.team_name = $1 ORDER BY u.user_id`, teamName)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+	res := make([]models.TeamMember, 0)
+	for rows.Next() {
+		var m models.TeamMember
+		if err := rows.Scan(&m.UserID, &m.Username, &m.IsActive, &m.Role, &m.Level); err != nil {
+			return nil, err
+		}
+		res = append(res, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, translateError(err, nil)
+	}
+	return res, nil
+}
+
+func (r *userRepoPG) ListMemberships(ctx context.Context, userID string) ([]models.Membership, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	rows, err := r.db(ctx).Query(ctx, `
+		SELECT team_name, user_id, is_active, role, level, joined_at
+		FROM team_members WHERE user_id = $1 ORDER BY team_name`, userID)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+	res := make([]models.Membership, 0)
+	for rows.Next() {
+		var m models.Membership
+		if err := rows.Scan(&m.TeamName, &m.UserID, &m.IsActive, &m.Role, &m.Level, &m.JoinedAt); err != nil {
+			return nil, err
+		}
+		res = append(res, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, translateError(err, nil)
+	}
 	return res, nil
 }
 
-func (r *userRepoPG) Update(ctx context.Context, id string, displayName *string, isActive *bool, teamName *string) error {
+func (r *userRepoPG) LevelsOf(ctx context.Context, teamName string, userIDs []string) (map[string]string, error) {
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
-	_, err := r.p.Exec(ctx, `UPDATE users SET display_name = COALESCE($1, display_name), is_active = COALESCE($2, is_active), team_name = COALESCE($3, team_name) WHERE user_id = $4`, displayName, isActive, teamName, id)
-	return err
+	rows, err := r.db(ctx).Query(ctx, `
+		SELECT DISTINCT ON (user_id) user_id, level
+		FROM team_members WHERE user_id = ANY($2)
+		ORDER BY user_id, team_name = $1 DESC,
+			array_position(ARRAY['junior', 'middle', 'senior', 'lead'], level) DESC`,
+		teamName, userIDs)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+	res := make(map[string]string, len(userIDs))
+	for rows.Next() {
+		var id, level string
+		if err := rows.Scan(&id, &level); err != nil {
+			return nil, err
+		}
+		res[id] = level
+	}
+	if err := rows.Err(); err != nil {
+		return nil, translateError(err, nil)
+	}
+	return res, nil
+}
+
+func (r *userRepoPG) AddMembership(ctx context.Context, m *models.Membership) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	err := r.db(ctx).QueryRow(ctx, `
+		INSERT INTO team_members (team_name, user_id, is_active, role, level)
+		VALUES ($1, $2, $3, COALESCE(NULLIF($4, ''), 'member'), COALESCE(NULLIF($5, ''), 'middle'))
+		ON CONFLICT (team_name, user_id) DO UPDATE SET
+			is_active = EXCLUDED.is_active,
+			role = COALESCE(NULLIF($4, ''), team_members.role),
+			level = COALESCE(NULLIF($5, ''), team_members.level)
+		RETURNING role, level, joined_at`,
+		m.TeamName, m.UserID, m.IsActive, m.Role, m.Level).Scan(&m.Role, &m.Level, &m.JoinedAt)
+	return translateError(err, nil)
+}
+
+func (r *userRepoPG) RemoveMembership(ctx context.Context, teamName string, userID string) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	tag, err := r.db(ctx).Exec(ctx, `DELETE FROM team_members WHERE team_name = $1 AND user_id = $2`, teamName, userID)
+	if err != nil {
+		return translateError(err, nil)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrMembershipNotFound
+	}
+	return nil
+}
+
+func (r *userRepoPG) SetMembershipsActive(ctx context.Context, teamName string, active bool) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	rows, err := r.db(ctx).Query(ctx, `
+		UPDATE team_members SET is_active = $2 WHERE team_name = $1
+		RETURNING user_id`, teamName, active)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+	res := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		res = append(res, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, translateError(err, nil)
+	}
+	return res, nil
+}
+
+func (r *userRepoPG) Update(ctx context.Context, id string, upd models.UserUpdate) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	_, err := r.db(ctx).Exec(ctx, `UPDATE users SET
+		display_name = COALESCE($1, display_name),
+		is_active = COALESCE($2, is_active),
+		max_open_reviews = CASE WHEN 'max_open_reviews' = ANY($10) THEN NULL ELSE COALESCE($3, max_open_reviews) END,
+		is_admin = COALESCE($4, is_admin),
+		calendar_path = CASE WHEN 'calendar_path' = ANY($10) THEN NULL ELSE COALESCE($5, calendar_path) END,
+		timezone = CASE WHEN 'timezone' = ANY($10) THEN NULL ELSE COALESCE($6, timezone) END,
+		work_start = CASE WHEN 'work_start' = ANY($10) THEN NULL ELSE COALESCE($7, work_start) END,
+		work_end = CASE WHEN 'work_end' = ANY($10) THEN NULL ELSE COALESCE($8, work_end) END
+		WHERE user_id = $9`,
+		upd.DisplayName, upd.IsActive, upd.MaxOpenReviews, upd.IsAdmin, upd.CalendarPath,
+		upd.Timezone, upd.WorkStart, upd.WorkEnd, id, upd.Clear)
+	return translateError(err, nil)
+}
+
+func (r *userRepoPG) SetSkills(ctx context.Context, id string, skills []string) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	if _, err := r.db(ctx).Exec(ctx, `DELETE FROM user_skills WHERE user_id = $1`, id); err != nil {
+		return translateError(err, nil)
+	}
+	_, err := r.db(ctx).Exec(ctx, `
+		INSERT INTO user_skills (user_id, skill)
+		SELECT $1, s FROM unnest($2::text[]) AS s`,
+		id, skills)
+	return translateError(err, nil)
+}
+
+func (r *userRepoPG) SetActive(ctx context.Context, ids []string, active bool) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	rows, err := r.db(ctx).Query(ctx, `UPDATE users SET is_active = $1 WHERE user_id = ANY($2) RETURNING user_id`, active, ids)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+	res := make([]string, 0, len(ids))
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		res = append(res, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, translateError(err, nil)
+	}
+	return res, nil
+}
+
+func (r *userRepoPG) LockCapacity(ctx context.Context, ids []string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	_, err := r.db(ctx).Exec(ctx, `
+		SELECT user_id FROM users
+		WHERE user_id = ANY($1) AND max_open_reviews IS NOT NULL
+		ORDER BY user_id
+		FOR UPDATE`,
+		ids)
+	return translateError(err, nil)
 }
 
 func (r *userRepoPG) Delete(ctx context.Context, id string) error {
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
-	_, err := r.p.Exec(ctx, `DELETE FROM users WHERE user_id = $1`, id)
-	return err
+	tag, err := r.db(ctx).Exec(ctx, `DELETE FROM users WHERE user_id = $1`, id)
+	if err != nil {
+		return translateError(err, nil)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
 }
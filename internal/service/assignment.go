@@ -0,0 +1,422 @@
+package service
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+// Assignment is the result of a reviewer selection: the picked reviewers and
+// the whole candidate pool they were picked from, each with their open review load.
+// AtCapacity lists teammates skipped because they reached max_open_reviews,
+// Absent those skipped because of an ongoing absence. OffHours lists the
+// picked reviewers who are outside their working hours right now. Excluded
+// lists teammates skipped because of a conflict of interest with the author.
+// Uncovered lists the required skills no reviewer of the PR has.
+type Assignment struct {
+	Reviewers  []models.Candidate `json:"reviewers"`
+	Candidates []models.Candidate `json:"candidates"`
+	AtCapacity []models.Candidate `json:"at_capacity,omitempty"`
+	Absent     []string           `json:"absent,omitempty"`
+	OffHours   []string           `json:"off_hours,omitempty"`
+	Excluded   []string           `json:"excluded,omitempty"`
+	Queued     int                `json:"queued,omitempty"`
+	Uncovered  []string           `json:"uncovered_skills,omitempty"`
+	// UnmetSeniority lists the team seniority rules the reviewers do not satisfy.
+	UnmetSeniority []string `json:"unmet_seniority,omitempty"`
+}
+
+func atCapacity(c models.Candidate) bool {
+	return c.MaxOpenReviews != nil && c.OpenReviews >= *c.MaxOpenReviews
+}
+
+// candidatePool holds what reviewer selection needs to know about a team.
+// It is loaded once and reused when many pull requests of the team are
+// staffed in one go; picked reviewers are added to the cached loads.
+type candidatePool struct {
+	teamName  string
+	fallback  bool
+	escalated bool
+	selector  ReviewerSelector
+	rotation  string
+	members   []models.User
+	loads     map[string]int
+	absent    map[string]bool
+	now       time.Time
+	// conflicts caches the excluded reviewers per author
+	exclusions repository.ExclusionRepository
+	conflicts  map[string]map[string]bool
+}
+
+func (s *prService) loadPool(ctx context.Context, teamName string, strategy string) (*candidatePool, error) {
+	team, err := effectiveTeam(ctx, s.teamRepo, teamName)
+	if err != nil {
+		return nil, err
+	}
+	selector, err := s.selectors.Resolve(strategy, team)
+	if err != nil {
+		return nil, err
+	}
+
+	teamUsers, err := s.userRepo.ListUsersByTeam(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	return s.newPool(ctx, teamName, selector, teamUsers)
+}
+
+// newPool loads the review loads and absences of the given members.
+func (s *prService) newPool(ctx context.Context, teamName string, selector ReviewerSelector, members []models.User) (*candidatePool, error) {
+	p := &candidatePool{
+		teamName: teamName,
+		selector: selector,
+		rotation: rotationTeam,
+		members:  members,
+		loads:    make(map[string]int),
+		absent:   make(map[string]bool),
+		now:      time.Now(),
+
+		exclusions: s.exclusionRepo,
+		conflicts:  make(map[string]map[string]bool),
+	}
+	if err := s.addLoads(ctx, p, members); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// addLoads adds the review loads and absences of the users to the pool.
+func (s *prService) addLoads(ctx context.Context, p *candidatePool, users []models.User) error {
+	ids := make([]string, 0, len(users))
+	var limited []string
+	for _, u := range users {
+		if u.IsActive {
+			ids = append(ids, u.UserID)
+			if u.MaxOpenReviews != nil {
+				limited = append(limited, u.UserID)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	// concurrent assignments must see each other's reviews before checking
+	// max_open_reviews, so the limited candidates stay locked until commit
+	if len(limited) > 0 {
+		if err := s.userRepo.LockCapacity(ctx, limited); err != nil {
+			return err
+		}
+	}
+	loads, err := s.prRepo.CountOpenReviews(ctx, ids)
+	if err != nil {
+		return err
+	}
+	absent, err := s.absenceRepo.AbsentAt(ctx, ids, p.now)
+	if err != nil {
+		return err
+	}
+	maps.Copy(p.loads, loads)
+	maps.Copy(p.absent, absent)
+	return nil
+}
+
+// excluded returns the users who must not review pull requests of the author.
+func (p *candidatePool) excluded(ctx context.Context, authorID string) (map[string]bool, error) {
+	if ex, ok := p.conflicts[authorID]; ok {
+		return ex, nil
+	}
+	ex, err := p.exclusions.ExcludedReviewers(ctx, authorID)
+	if err != nil {
+		return nil, err
+	}
+	p.conflicts[authorID] = ex
+	return ex, nil
+}
+
+// with returns a pool over other members sharing the loads of p, so picks
+// from either are counted in both.
+func (p *candidatePool) with(members []models.User) *candidatePool {
+	q := *p
+	q.members = members
+	return &q
+}
+
+// pick chooses up to count reviewers among active members, skipping the
+// author, the users in exclude and those with a conflict of interest with
+// the author. The strategy picks among members inside
+// their working hours; missing reviewers are taken from those starting soonest.
+func (p *candidatePool) pick(ctx context.Context, authorID string, exclude []string, count int) (*Assignment, error) {
+	skip := make(map[string]bool, len(exclude)+1)
+	skip[authorID] = true
+	for _, id := range exclude {
+		skip[id] = true
+	}
+	conflicts, err := p.excluded(ctx, authorID)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]models.Candidate, 0, len(p.members))
+	var full []models.Candidate
+	var absent, excluded []string
+	for _, u := range p.members {
+		if !u.IsActive || skip[u.UserID] {
+			continue
+		}
+		if conflicts[u.UserID] {
+			excluded = append(excluded, u.UserID)
+			continue
+		}
+		if p.absent[u.UserID] {
+			absent = append(absent, u.UserID)
+			continue
+		}
+		c := models.Candidate{
+			User:        u,
+			OpenReviews: p.loads[u.UserID],
+			Fallback:    p.fallback,
+			Escalated:   p.escalated,
+		}
+		if atCapacity(c) {
+			full = append(full, c)
+			continue
+		}
+		candidates = append(candidates, c)
+	}
+	candidates = sortByLoad(candidates)
+	onShift, later := splitByShift(candidates, p.now)
+
+	reviewers, err := p.selector.Select(ctx, SelectionRequest{
+		TeamName:   p.teamName,
+		Rotation:   p.rotation,
+		AuthorID:   authorID,
+		Candidates: onShift,
+		Count:      count,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var offHours []string
+	for _, c := range limit(later, max(count-len(reviewers), 0)) {
+		reviewers = append(reviewers, c)
+		offHours = append(offHours, c.UserID)
+	}
+	for _, r := range reviewers {
+		p.loads[r.UserID]++
+	}
+	return &Assignment{
+		Reviewers:  reviewers,
+		Candidates: candidates,
+		AtCapacity: sortByLoad(full),
+		Absent:     absent,
+		OffHours:   offHours,
+		Excluded:   excluded,
+	}, nil
+}
+
+// poolChain is the ordered list of teams reviewers are drawn from: the PR's
+// team first, then its fallback teams by priority, then its parent teams up
+// the hierarchy. Pools are loaded on demand.
+type poolChain struct {
+	s         *prService
+	strategy  string
+	teams     []string
+	fallbacks int
+	pools     []*candidatePool
+}
+
+func (s *prService) loadChain(ctx context.Context, teamName string, strategy string) (*poolChain, error) {
+	pool, err := s.loadPool(ctx, teamName, strategy)
+	if err != nil {
+		return nil, err
+	}
+	fallbacks, err := s.teamRepo.ListFallbacks(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	ancestors, err := s.teamRepo.ListAncestors(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+
+	teams := append([]string{teamName}, fallbacks...)
+	for _, a := range ancestors[1:] {
+		teams = append(teams, a.TeamName)
+	}
+	return &poolChain{
+		s:         s,
+		strategy:  strategy,
+		teams:     teams,
+		fallbacks: len(fallbacks),
+		pools:     []*candidatePool{pool},
+	}, nil
+}
+
+func (c *poolChain) pool(ctx context.Context, i int) (*candidatePool, error) {
+	if i < len(c.pools) {
+		return c.pools[i], nil
+	}
+	p, err := c.s.loadPool(ctx, c.teams[i], c.strategy)
+	if err != nil {
+		return nil, err
+	}
+	if i <= c.fallbacks {
+		p.fallback = true
+	} else {
+		p.escalated = true
+	}
+	// seniority rules judge reviewers by their level in the PR's team, so
+	// members of other teams carry that level rather than their own
+	levels, err := c.s.userRepo.LevelsOf(ctx, c.teams[0], userIDs(p.members))
+	if err != nil {
+		return nil, err
+	}
+	for j := range p.members {
+		p.members[j].Level = levels[p.members[j].UserID]
+	}
+	c.pools = append(c.pools, p)
+	return p, nil
+}
+
+// pick takes reviewers from the PR's team and, while some are still missing,
+// from the fallback teams in order.
+func (c *poolChain) pick(ctx context.Context, authorID string, exclude []string, count int) (*Assignment, error) {
+	skip := append([]string(nil), exclude...)
+	result := &Assignment{Reviewers: []models.Candidate{}, Candidates: []models.Candidate{}}
+	for i := range c.teams {
+		p, err := c.pool(ctx, i)
+		if err != nil {
+			return nil, err
+		}
+		a, err := p.pick(ctx, authorID, skip, count-len(result.Reviewers))
+		if err != nil {
+			return nil, err
+		}
+		result.merge(a)
+		if len(result.Reviewers) >= count {
+			break
+		}
+		for _, r := range a.Reviewers {
+			skip = append(skip, r.UserID)
+		}
+	}
+	return result, nil
+}
+
+// pickWhere takes one reviewer among the members passing match, searching
+// the teams in order. It returns an empty Assignment when nobody qualifies.
+func (c *poolChain) pickWhere(ctx context.Context, authorID string, exclude []string, match func(models.User) bool) (*Assignment, error) {
+	for i := range c.teams {
+		p, err := c.pool(ctx, i)
+		if err != nil {
+			return nil, err
+		}
+		var members []models.User
+		for _, u := range p.members {
+			if match(u) {
+				members = append(members, u)
+			}
+		}
+		a, err := p.with(members).pick(ctx, authorID, exclude, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(a.Reviewers) > 0 {
+			return a, nil
+		}
+	}
+	return &Assignment{Reviewers: []models.Candidate{}, Candidates: []models.Candidate{}}, nil
+}
+
+// merge adds the outcome of another selection phase to a. Users seen by
+// several phases are listed once, as the earlier phase saw them.
+func (a *Assignment) merge(o *Assignment) {
+	a.Reviewers = append(a.Reviewers, o.Reviewers...)
+	a.Candidates = appendCandidates(a.Candidates, o.Candidates)
+	a.AtCapacity = appendCandidates(a.AtCapacity, o.AtCapacity)
+	a.Absent = dedupe(append(a.Absent, o.Absent...))
+	a.OffHours = dedupe(append(a.OffHours, o.OffHours...))
+	a.Excluded = dedupe(append(a.Excluded, o.Excluded...))
+}
+
+// appendCandidates appends the candidates of src not yet in dst.
+func appendCandidates(dst, src []models.Candidate) []models.Candidate {
+	for _, c := range src {
+		if !slices.ContainsFunc(dst, func(d models.Candidate) bool { return d.UserID == c.UserID }) {
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
+
+// selectReviewers picks up to count reviewers among active team members,
+// skipping the users in exclude, with the strategy resolved for the team.
+// Missing reviewers are taken from the team's fallback and parent teams.
+func (s *prService) selectReviewers(ctx context.Context, teamName string, authorID string, exclude []string, count int, strategy string) (*Assignment, error) {
+	chain, err := s.loadChain(ctx, teamName, strategy)
+	if err != nil {
+		return nil, err
+	}
+	return chain.pick(ctx, authorID, exclude, count)
+}
+
+// DrainReviewQueue fills reviewer slots of queued pull requests, oldest
+// first, as long as someone in the team has capacity again. Every pull
+// request is handled in its own transaction; failures are logged and the PR
+// stays queued, so one broken PR does not hold up the rest of the queue.
+// It must not be called inside a transaction.
+func (s *prService) DrainReviewQueue(ctx context.Context) {
+	queued, err := s.prRepo.ListQueued(ctx)
+	if err != nil {
+		s.log.Error("DrainReviewQueue", zap.Error(err))
+		return
+	}
+	for _, q := range queued {
+		err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+			return s.fillQueued(ctx, q)
+		})
+		if err != nil {
+			s.log.Error("DrainReviewQueue", zap.String("pull_request_id", q.PullRequestID), zap.Error(err))
+		}
+	}
+}
+
+// fillQueued assigns reviewers to the free slots of one queued pull request.
+func (s *prService) fillQueued(ctx context.Context, q models.QueuedReview) error {
+	pr, err := s.prRepo.GetByIDForUpdate(ctx, q.PullRequestID)
+	if err != nil {
+		return err
+	}
+	if pr.Status != models.PRStatusOpen {
+		return nil
+	}
+	current, err := s.prRepo.ListReviewers(ctx, pr.PullRequestID)
+	if err != nil {
+		return err
+	}
+	assignment, err := s.selectReviewers(ctx, pr.TeamName, pr.AuthorID, userIDs(current), q.MissingReviewers, "")
+	if err != nil {
+		return err
+	}
+	for _, r := range assignment.Reviewers {
+		if err := s.prRepo.AddReviewer(ctx, pr.PullRequestID, r.UserID); err != nil {
+			return err
+		}
+	}
+
+	missing := q.MissingReviewers - len(assignment.Reviewers)
+	switch {
+	case missing == q.MissingReviewers:
+		return nil
+	case missing > 0 && len(assignment.AtCapacity) > 0:
+		return s.prRepo.EnqueueReviewers(ctx, pr.PullRequestID, missing)
+	default:
+		return s.prRepo.Dequeue(ctx, pr.PullRequestID)
+	}
+}
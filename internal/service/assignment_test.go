@@ -0,0 +1,78 @@
+package service
+
+import (
+	"slices"
+	"testing"
+
+	"pr-reviewer/internal/models"
+)
+
+func candidates(ids ...string) []models.Candidate {
+	res := make([]models.Candidate, 0, len(ids))
+	for _, id := range ids {
+		res = append(res, models.Candidate{User: models.User{UserID: id}})
+	}
+	return res
+}
+
+func candidateIDs(cs []models.Candidate) []string {
+	ids := make([]string, 0, len(cs))
+	for _, c := range cs {
+		ids = append(ids, c.UserID)
+	}
+	return ids
+}
+
+func TestAssignmentMerge(t *testing.T) {
+	tests := []struct {
+		name       string
+		phases     []*Assignment
+		reviewers  []string
+		candidates []string
+		atCapacity []string
+		absent     []string
+	}{
+		{
+			name: "disjoint phases",
+			phases: []*Assignment{
+				{Reviewers: candidates("a"), Candidates: candidates("a", "b")},
+				{Reviewers: candidates("c"), Candidates: candidates("c", "d")},
+			},
+			reviewers:  []string{"a", "c"},
+			candidates: []string{"a", "b", "c", "d"},
+		},
+		{
+			name: "team seen by every phase",
+			phases: []*Assignment{
+				{Reviewers: candidates("a"), Candidates: candidates("a", "b", "c"), Absent: []string{"x"}},
+				{Candidates: candidates("b", "c"), Absent: []string{"x"}},
+				{Reviewers: candidates("b"), Candidates: candidates("a", "b", "c"), AtCapacity: candidates("z")},
+				{Candidates: candidates("c"), AtCapacity: candidates("z"), Absent: []string{"x", "y"}},
+			},
+			reviewers:  []string{"a", "b"},
+			candidates: []string{"a", "b", "c"},
+			atCapacity: []string{"z"},
+			absent:     []string{"x", "y"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Assignment{Reviewers: []models.Candidate{}, Candidates: []models.Candidate{}}
+			for _, p := range tt.phases {
+				a.merge(p)
+			}
+			if got := candidateIDs(a.Reviewers); !slices.Equal(got, tt.reviewers) {
+				t.Errorf("reviewers = %v, want %v", got, tt.reviewers)
+			}
+			if got := candidateIDs(a.Candidates); !slices.Equal(got, tt.candidates) {
+				t.Errorf("candidates = %v, want %v", got, tt.candidates)
+			}
+			if got := candidateIDs(a.AtCapacity); !slices.Equal(got, tt.atCapacity) {
+				t.Errorf("at_capacity = %v, want %v", got, tt.atCapacity)
+			}
+			if !slices.Equal(a.Absent, tt.absent) {
+				t.Errorf("absent = %v, want %v", a.Absent, tt.absent)
+			}
+		})
+	}
+}
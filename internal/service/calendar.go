@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"pr-reviewer/internal/apperr"
+	"pr-reviewer/internal/ical"
+	"pr-reviewer/internal/models"
+)
+
+var (
+	ErrInvalidCalendar       = apperr.New(apperr.CodeValidation, "invalid iCalendar data")
+	ErrCalendarNotConfigured = apperr.New(apperr.CodeValidation, "calendar sync is not configured for this user")
+	ErrInvalidCalendarPath   = apperr.New(apperr.CodeValidation, "calendar_path must be a relative path inside the calendar directory")
+	ErrCalendarFileNotFound  = apperr.New(apperr.CodeNotFound, "calendar file not found")
+)
+
+// CalendarImport is the result of an ICS import. Skipped counts the events
+// that are not out-of-office or have no valid time range.
+type CalendarImport struct {
+	Imported []models.Absence `json:"imported"`
+	Skipped  int              `json:"skipped"`
+}
+
+// ImportCalendar replaces the user's imported absences with the out-of-office
+// events of the calendar. Manually entered absences are kept.
+func (s *userService) ImportCalendar(ctx context.Context, userID string, r io.Reader) (*CalendarImport, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	// all-day events cover whole days of the user's time zone
+	events, err := ical.Parse(r, userLocation(*user))
+	if err != nil {
+		return nil, apperr.Wrap(err, apperr.CodeValidation, ErrInvalidCalendar.Message)
+	}
+
+	result := &CalendarImport{Imported: make([]models.Absence, 0, len(events))}
+	for _, e := range events {
+		if !e.OutOfOffice() || !e.End.After(e.Start) {
+			result.Skipped++
+			continue
+		}
+		a := models.Absence{
+			UserID:   userID,
+			Kind:     absenceKind(e),
+			StartsAt: e.Start,
+			EndsAt:   e.End,
+			Source:   models.AbsenceSourceICS,
+		}
+		if e.Summary != "" {
+			a.Note = &e.Summary
+		}
+		if e.UID != "" {
+			a.ExternalUID = &e.UID
+		}
+		result.Imported = append(result.Imported, a)
+	}
+
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		return s.absences.ReplaceImported(ctx, userID, models.AbsenceSourceICS, result.Imported)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// SyncCalendar imports the ICS file configured in the user's calendar_path.
+func (s *userService) SyncCalendar(ctx context.Context, userID string) (*CalendarImport, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.CalendarPath == nil {
+		return nil, ErrCalendarNotConfigured
+	}
+	path, err := s.calendarFile(*user.CalendarPath)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrCalendarFileNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return s.ImportCalendar(ctx, userID, f)
+}
+
+// calendarFile resolves a configured calendar path inside calendarDir.
+func (s *userService) calendarFile(rel string) (string, error) {
+	if s.calendarDir == "" {
+		return "", ErrCalendarNotConfigured
+	}
+	if !filepath.IsLocal(rel) {
+		return "", ErrInvalidCalendarPath
+	}
+	return filepath.Join(s.calendarDir, rel), nil
+}
+
+// absenceKind guesses the kind from the event categories and summary.
+func absenceKind(e ical.Event) models.AbsenceKind {
+	text := strings.ToLower(e.Summary + " " + strings.Join(e.Categories, " "))
+	switch {
+	case strings.Contains(text, "sick"):
+		return models.AbsenceSickLeave
+	case strings.Contains(text, "vacation"), strings.Contains(text, "holiday"):
+		return models.AbsenceVacation
+	default:
+		return models.AbsenceOther
+	}
+}
@@ -0,0 +1,312 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"pr-reviewer/internal/apperr"
+	"pr-reviewer/internal/codeowners"
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/repository"
+)
+
+var ErrInvalidPath = apperr.New(apperr.CodeValidation, "changed paths must not be empty")
+
+// CodeOwners is the parsed CODEOWNERS file of a team.
+type CodeOwners struct {
+	TeamName string                  `json:"team_name"`
+	Rules    []models.CodeOwnersRule `json:"rules"`
+}
+
+func codeOwnersOf(teamName string, rules codeowners.Ruleset) *CodeOwners {
+	out := &CodeOwners{TeamName: teamName, Rules: make([]models.CodeOwnersRule, 0, len(rules))}
+	for _, r := range rules {
+		owners := make([]string, 0, len(r.Owners))
+		for _, o := range r.Owners {
+			owners = append(owners, o.Handle)
+		}
+		out.Rules = append(out.Rules, models.CodeOwnersRule{Pattern: r.Pattern, Owners: owners, Line: r.Line})
+	}
+	return out
+}
+
+func (s *teamService) GetCodeOwners(ctx context.Context, name string) (*CodeOwners, error) {
+	if _, err := s.teams.GetByName(ctx, name); err != nil {
+		return nil, err
+	}
+	content, err := s.teams.GetCodeOwners(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := codeowners.Parse(strings.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	return codeOwnersOf(name, rules), nil
+}
+
+// SetCodeOwners validates and stores the team's CODEOWNERS file. Sub-teams
+// without a file of their own use it as well.
+func (s *teamService) SetCodeOwners(ctx context.Context, name string, r io.Reader) (*CodeOwners, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := codeowners.Parse(bytes.NewReader(data))
+	if err != nil {
+		// the message carries the offending line
+		return nil, apperr.Wrap(err, apperr.CodeValidation, err.Error())
+	}
+	if _, err := s.teams.GetByName(ctx, name); err != nil {
+		return nil, err
+	}
+	if err := s.teams.SetCodeOwners(ctx, name, string(data)); err != nil {
+		return nil, err
+	}
+	return codeOwnersOf(name, rules), nil
+}
+
+// cleanPaths normalizes changed paths to slash separated paths relative to
+// the repository root and drops duplicates.
+func cleanPaths(paths []string) ([]string, error) {
+	seen := make(map[string]bool, len(paths))
+	out := make([]string, 0, len(paths))
+	for _, p := range paths {
+		p = strings.TrimPrefix(path.Clean("/"+strings.TrimSpace(p)), "/")
+		if p == "" {
+			return nil, ErrInvalidPath
+		}
+		if !seen[p] {
+			seen[p] = true
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+// codeOwnersFor returns the rules for the team's pull requests: the team's
+// own CODEOWNERS file or else the one of the closest parent team.
+func (s *prService) codeOwnersFor(ctx context.Context, teamName string) (codeowners.Ruleset, error) {
+	teams, err := s.teamRepo.ListAncestors(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range teams {
+		content, err := s.teamRepo.GetCodeOwners(ctx, t.TeamName)
+		if errors.Is(err, repository.ErrCodeOwnersNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return codeowners.Parse(strings.NewReader(content))
+	}
+	return nil, nil
+}
+
+// ownedPaths is a CODEOWNERS rule with the touched paths it owns.
+type ownedPaths struct {
+	rule  codeowners.Rule
+	paths []string
+}
+
+// groupByOwner matches the paths against the rules, most touched rule first.
+// Unowned paths are left out.
+func groupByOwner(rules codeowners.Ruleset, paths []string) []ownedPaths {
+	byLine := make(map[int]*ownedPaths)
+	var groups []*ownedPaths
+	for _, p := range paths {
+		rule, ok := rules.Match(p)
+		if !ok || len(rule.Owners) == 0 {
+			continue
+		}
+		g, ok := byLine[rule.Line]
+		if !ok {
+			g = &ownedPaths{rule: rule}
+			byLine[rule.Line] = g
+			groups = append(groups, g)
+		}
+		g.paths = append(g.paths, p)
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return len(groups[i].paths) > len(groups[j].paths) })
+
+	out := make([]ownedPaths, 0, len(groups))
+	for _, g := range groups {
+		out = append(out, *g)
+	}
+	return out
+}
+
+// resolveOwners looks up the users behind every owner of the groups, keyed
+// by owner handle. Users are matched by user_id or username, teams by name.
+func (s *prService) resolveOwners(ctx context.Context, groups []ownedPaths) (map[string][]models.User, error) {
+	var handles []string
+	teams := make(map[string]bool)
+	for _, g := range groups {
+		for _, o := range g.rule.Owners {
+			if o.Team {
+				teams[o.Name] = true
+			} else {
+				handles = append(handles, o.Name)
+			}
+		}
+	}
+
+	users, err := s.userRepo.ListByHandles(ctx, handles)
+	if err != nil {
+		return nil, err
+	}
+	members := make(map[string][]models.User, len(teams))
+	for team := range teams {
+		if members[team], err = s.userRepo.ListUsersByTeam(ctx, team); err != nil {
+			return nil, err
+		}
+	}
+
+	owners := make(map[string][]models.User)
+	for _, g := range groups {
+		for _, o := range g.rule.Owners {
+			if _, ok := owners[o.Handle]; ok {
+				continue
+			}
+			if o.Team {
+				owners[o.Handle] = members[o.Name]
+				continue
+			}
+			for _, u := range users {
+				if u.UserID == o.Name || u.Username == o.Name {
+					owners[o.Handle] = append(owners[o.Handle], u)
+				}
+			}
+		}
+	}
+	return owners, nil
+}
+
+// selectOwners picks up to count reviewers among the code owners of the
+// paths the pull request touches, one per owning rule, most touched rule
+// first. Rules already owned by a reviewer in exclude are skipped. Owners
+// are held to the same activity, absence, capacity and working hours checks
+// as team members.
+func (s *prService) selectOwners(ctx context.Context, chain *poolChain, pr *models.PullRequest, exclude []string, count int) (*Assignment, error) {
+	result := &Assignment{Reviewers: []models.Candidate{}, Candidates: []models.Candidate{}}
+	if count <= 0 {
+		return result, nil
+	}
+	paths, err := s.prRepo.ListFiles(ctx, pr.PullRequestID)
+	if err != nil || len(paths) == 0 {
+		return result, err
+	}
+	rules, err := s.codeOwnersFor(ctx, pr.TeamName)
+	if err != nil {
+		return nil, err
+	}
+	groups := groupByOwner(rules, paths)
+	if len(groups) == 0 {
+		return result, nil
+	}
+	owners, err := s.resolveOwners(ctx, groups)
+	if err != nil {
+		return nil, err
+	}
+
+	var all []models.User
+	seen := make(map[string]bool)
+	for _, users := range owners {
+		for _, u := range users {
+			if !seen[u.UserID] {
+				seen[u.UserID] = true
+				all = append(all, u)
+			}
+		}
+	}
+	team, err := chain.pool(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := s.ownerPool(ctx, team, all)
+	if err != nil {
+		return nil, err
+	}
+
+	covered := make(map[string]bool, len(exclude))
+	for _, id := range exclude {
+		covered[id] = true
+	}
+	skip := append([]string(nil), exclude...)
+	for _, g := range groups {
+		if len(result.Reviewers) >= count {
+			break
+		}
+		handleOf := make(map[string]string)
+		var members []models.User
+		for _, o := range g.rule.Owners {
+			for _, u := range owners[o.Handle] {
+				if _, ok := handleOf[u.UserID]; !ok {
+					handleOf[u.UserID] = o.Handle
+					members = append(members, u)
+				}
+			}
+		}
+		if slices.ContainsFunc(members, func(u models.User) bool { return covered[u.UserID] }) {
+			continue
+		}
+
+		a, err := pool.with(members).pick(ctx, pr.AuthorID, skip, 1)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range a.Reviewers {
+			r.CodeOwner = &models.OwnerMatch{
+				Pattern: g.rule.Pattern,
+				Line:    g.rule.Line,
+				Owner:   handleOf[r.UserID],
+				Paths:   g.paths,
+			}
+			result.Reviewers = append(result.Reviewers, r)
+			covered[r.UserID] = true
+			skip = append(skip, r.UserID)
+		}
+		result.OffHours = append(result.OffHours, a.OffHours...)
+	}
+
+	// list every owner once rather than once per rule
+	listing, err := pool.pick(ctx, pr.AuthorID, exclude, 0)
+	if err != nil {
+		return nil, err
+	}
+	result.Candidates = listing.Candidates
+	result.AtCapacity = listing.AtCapacity
+	result.Absent = listing.Absent
+	result.Excluded = listing.Excluded
+	return result, nil
+}
+
+// ownerPool narrows the pool of the PR's team to the code owners. It shares
+// the loads of the team pool, so that owners and teammates picked for the
+// same PR are counted once, and keeps a round robin rotation of its own.
+// Owners from outside the team have their loads added to the shared ones.
+func (s *prService) ownerPool(ctx context.Context, team *candidatePool, owners []models.User) (*candidatePool, error) {
+	inTeam := make(map[string]bool, len(team.members))
+	for _, u := range team.members {
+		inTeam[u.UserID] = true
+	}
+	var outside []models.User
+	for _, u := range owners {
+		if !inTeam[u.UserID] {
+			outside = append(outside, u)
+		}
+	}
+	if err := s.addLoads(ctx, team, outside); err != nil {
+		return nil, err
+	}
+	pool := team.with(owners)
+	pool.rotation = rotationCodeOwners
+	return pool, nil
+}
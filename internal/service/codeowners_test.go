@@ -0,0 +1,57 @@
+package service_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/service"
+)
+
+func TestOwnersKeepTeamRotation(t *testing.T) {
+	ts := newTestServices(t)
+	ctx := context.Background()
+
+	_, err := ts.team.AddTeam(ctx, "core", []models.TeamMember{
+		{UserID: "a", Username: "a", IsActive: true},
+		{UserID: "b", Username: "b", IsActive: true},
+		{UserID: "c", Username: "c", IsActive: true},
+		{UserID: "d", Username: "d", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("AddTeam: %v", err)
+	}
+	if _, err := ts.team.UpdateSettings(ctx, "core", models.TeamSettings{ReviewerStrategy: strPtr(service.StrategyRoundRobin)}); err != nil {
+		t.Fatalf("UpdateSettings: %v", err)
+	}
+	if _, err := ts.team.SetCodeOwners(ctx, "core", strings.NewReader("/docs/ @d\n")); err != nil {
+		t.Fatalf("SetCodeOwners: %v", err)
+	}
+
+	create := func(id string, count int, paths ...string) []string {
+		t.Helper()
+		pr, _, err := ts.pr.CreatePR(ctx, service.CreatePRInput{
+			PullRequestID: id, Name: id, AuthorID: "a", ReviewerCount: &count, Paths: paths,
+		})
+		if err != nil {
+			t.Fatalf("CreatePR(%s): %v", id, err)
+		}
+		got := slices.Clone(pr.AssignedReviewers)
+		slices.Sort(got)
+		return got
+	}
+
+	if got := create("pr-1", 2); !slices.Equal(got, []string{"b", "c"}) {
+		t.Fatalf("pr-1 reviewers = %v, want [b c]", got)
+	}
+	// the owner is picked in the code owners rotation
+	if got := create("pr-2", 1, "docs/guide.md"); !slices.Equal(got, []string{"d"}) {
+		t.Fatalf("pr-2 reviewers = %v, want [d]", got)
+	}
+	// so the team rotation goes on after c
+	if got := create("pr-3", 2); !slices.Equal(got, []string{"b", "d"}) {
+		t.Errorf("pr-3 reviewers = %v, want [b d]", got)
+	}
+}
@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"slices"
+
+	"pr-reviewer/internal/apperr"
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/repository"
+)
+
+var (
+	ErrNothingToDeactivate         = apperr.New(apperr.CodeValidation, "user_ids or team_name required")
+	ErrUnknownDeactivationBehavior = apperr.New(apperr.CodeValidation, "on_reviewer_deactivation must be one of reassign, leave, flag")
+)
+
+// What happens to the OPEN reviews of a member who becomes inactive.
+const (
+	DeactivationReassign = "reassign"
+	DeactivationLeave    = "leave"
+	DeactivationFlag     = "flag"
+)
+
+func validDeactivationBehavior(b string) bool {
+	return b == DeactivationReassign || b == DeactivationLeave || b == DeactivationFlag
+}
+
+// deactivationBehavior returns the team setting, reassign by default.
+func deactivationBehavior(team *models.Team) string {
+	if team != nil && team.OnReviewerDeactivation != nil {
+		return *team.OnReviewerDeactivation
+	}
+	return DeactivationReassign
+}
+
+// DeactivateInput selects what to deactivate: the listed users everywhere
+// and, when TeamName is set, every membership of that team. Members of the
+// team stay active in their other teams.
+type DeactivateInput struct {
+	UserIDs  []string
+	TeamName string
+}
+
+// DeactivationReport tells what happened to the open reviews of deactivated
+// users. Flagged and Left list reviews kept as is because of the team setting.
+type DeactivationReport struct {
+	Deactivated      []string                `json:"deactivated"`
+	Reassigned       []models.ReviewerChange `json:"reassigned"`
+	WithoutCandidate []models.ReviewerChange `json:"without_candidate"`
+	Flagged          []models.ReviewerChange `json:"flagged"`
+	Left             []models.ReviewerChange `json:"left"`
+}
+
+func (s *prService) DeactivateUsers(ctx context.Context, in DeactivateInput) (*DeactivationReport, error) {
+	if len(in.UserIDs) == 0 && in.TeamName == "" {
+		return nil, ErrNothingToDeactivate
+	}
+
+	var report *DeactivationReport
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		report = newDeactivationReport()
+		ids := dedupe(in.UserIDs)
+		if len(ids) > 0 {
+			found, err := s.userRepo.SetActive(ctx, ids, false)
+			if err != nil {
+				return err
+			}
+			if len(found) != len(ids) {
+				return repository.ErrUserNotFound
+			}
+			handed, err := s.HandOffReviews(ctx, ids, "")
+			if err != nil {
+				return err
+			}
+			report.add(handed)
+		}
+
+		if in.TeamName != "" {
+			if _, err := s.teamRepo.GetByName(ctx, in.TeamName); err != nil {
+				return err
+			}
+			members, err := s.userRepo.SetMembershipsActive(ctx, in.TeamName, false)
+			if err != nil {
+				return err
+			}
+			// reviews of users deactivated everywhere are handed off already
+			members = slices.DeleteFunc(members, func(id string) bool { return slices.Contains(ids, id) })
+			handed, err := s.HandOffReviews(ctx, members, in.TeamName)
+			if err != nil {
+				return err
+			}
+			report.add(handed)
+			ids = append(ids, members...)
+		}
+		report.Deactivated = ids
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.DrainReviewQueue(ctx)
+	return report, nil
+}
+
+// dedupe returns ids without repetitions, in their original order.
+func dedupe(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	res := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			res = append(res, id)
+		}
+	}
+	return res
+}
+
+func newDeactivationReport() *DeactivationReport {
+	return &DeactivationReport{
+		Deactivated:      []string{},
+		Reassigned:       []models.ReviewerChange{},
+		WithoutCandidate: []models.ReviewerChange{},
+		Flagged:          []models.ReviewerChange{},
+		Left:             []models.ReviewerChange{},
+	}
+}
+
+// add appends the review outcomes of o to r.
+func (r *DeactivationReport) add(o *DeactivationReport) {
+	r.Reassigned = append(r.Reassigned, o.Reassigned...)
+	r.WithoutCandidate = append(r.WithoutCandidate, o.WithoutCandidate...)
+	r.Flagged = append(r.Flagged, o.Flagged...)
+	r.Left = append(r.Left, o.Left...)
+}
+
+// HandOffReviews applies the team deactivation setting to every OPEN pull
+// request reviewed by the given (already inactive) users and records the
+// outcome in the PR history. Candidate pools are loaded once per team and
+// the changes are written in bulk, so the number of queries does not grow
+// with the number of PRs. Replacements keep the team seniority rules met
+// when a candidate allows it. Reviews nobody can take over stay with the
+// old reviewer and are flagged. A non-empty teamName limits the hand-off to
+// the team's pull requests, for users whose membership was deactivated.
+func (s *prService) HandOffReviews(ctx context.Context, userIDs []string, teamName string) (*DeactivationReport, error) {
+	report := newDeactivationReport()
+	if len(userIDs) == 0 {
+		return report, nil
+	}
+	var events []*models.PREvent
+	prs, err := s.prRepo.LockOpenByReviewers(ctx, userIDs, teamName)
+	if err != nil {
+		return nil, err
+	}
+
+	isLeaving := make(map[string]bool, len(userIDs))
+	for _, id := range userIDs {
+		isLeaving[id] = true
+	}
+	// authors and reviewers per team, whose levels the seniority rules need
+	teamUsers := make(map[string][]string)
+	for _, pr := range prs {
+		teamUsers[pr.TeamName] = append(teamUsers[pr.TeamName], pr.AuthorID)
+		teamUsers[pr.TeamName] = append(teamUsers[pr.TeamName], pr.AssignedReviewers...)
+	}
+	chains := make(map[string]*poolChain)
+	behaviors := make(map[string]string)
+	seniority := make(map[string]*teamSeniority)
+
+	for _, pr := range prs {
+		behavior, ok := behaviors[pr.TeamName]
+		if !ok {
+			team, err := effectiveTeam(ctx, s.teamRepo, pr.TeamName)
+			if err != nil {
+				return nil, err
+			}
+			behavior = deactivationBehavior(team)
+			behaviors[pr.TeamName] = behavior
+			if behavior == DeactivationReassign {
+				sen, err := s.teamSeniority(ctx, team, pr.TeamName, dedupe(teamUsers[pr.TeamName]))
+				if err != nil {
+					return nil, err
+				}
+				seniority[pr.TeamName] = sen
+			}
+		}
+
+		exclude := append([]string(nil), pr.AssignedReviewers...)
+		current := make([]models.User, 0, len(pr.AssignedReviewers))
+		for _, id := range pr.AssignedReviewers {
+			current = append(current, models.User{UserID: id})
+		}
+		for _, old := range pr.AssignedReviewers {
+			if !isLeaving[old] {
+				continue
+			}
+			change := models.ReviewerChange{PullRequestID: pr.PullRequestID, OldReviewerID: old}
+
+			switch behavior {
+			case DeactivationLeave:
+				report.Left = append(report.Left, change)
+				if events, err = appendEvent(events, pr.PullRequestID, models.EventReviewerKept, change); err != nil {
+					return nil, err
+				}
+				continue
+			case DeactivationFlag:
+				report.Flagged = append(report.Flagged, change)
+				if events, err = appendEvent(events, pr.PullRequestID, models.EventReviewerInactive, change); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			chain, ok := chains[pr.TeamName]
+			if !ok {
+				if chain, err = s.loadChain(ctx, pr.TeamName, ""); err != nil {
+					return nil, err
+				}
+				chains[pr.TeamName] = chain
+			}
+			// prefer a replacement that keeps the team seniority rules met
+			var rule func(models.User) bool
+			if sen := seniority[pr.TeamName]; !sen.none() {
+				rule = sen.replacementRule(pr.AuthorID, current, old)
+			}
+			assignment := &Assignment{}
+			if rule != nil {
+				if assignment, err = chain.pickWhere(ctx, pr.AuthorID, exclude, rule); err != nil {
+					return nil, err
+				}
+			}
+			if len(assignment.Reviewers) == 0 {
+				if assignment, err = chain.pick(ctx, pr.AuthorID, exclude, 1); err != nil {
+					return nil, err
+				}
+			}
+			if len(assignment.Reviewers) == 0 {
+				report.WithoutCandidate = append(report.WithoutCandidate, change)
+				if events, err = appendEvent(events, pr.PullRequestID, models.EventReviewerInactive, change); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			change.NewReviewerID = assignment.Reviewers[0].UserID
+			exclude = append(exclude, change.NewReviewerID)
+			if sen := seniority[pr.TeamName]; !sen.none() {
+				sen.remember(assignment.Reviewers[0].User)
+			}
+			for i := range current {
+				if current[i].UserID == old {
+					current[i] = assignment.Reviewers[0].User
+				}
+			}
+			report.Reassigned = append(report.Reassigned, change)
+			if events, err = appendEvent(events, pr.PullRequestID, models.EventReviewerReplaced, change); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := s.prRepo.ReplaceReviewers(ctx, report.Reassigned); err != nil {
+		return nil, err
+	}
+	if err := s.eventRepo.CreateMany(ctx, events); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func appendEvent(events []*models.PREvent, prID string, typ models.PREventType, details any) ([]*models.PREvent, error) {
+	e, err := newEvent(prID, typ, "", details)
+	if err != nil {
+		return nil, err
+	}
+	return append(events, e), nil
+}
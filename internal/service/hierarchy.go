@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"pr-reviewer/internal/apperr"
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/repository"
+)
+
+var ErrTeamCycle = apperr.New(apperr.CodeValidation, "parent_team would create a cycle")
+
+// effectiveTeam returns the team with unset settings inherited from its
+// nearest ancestor that sets them.
+func effectiveTeam(ctx context.Context, teams repository.TeamRepository, name string) (*models.Team, error) {
+	chain, err := teams.ListAncestors(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	team := chain[0]
+	for _, parent := range chain[1:] {
+		inheritSettings(&team.TeamSettings, parent.TeamSettings)
+	}
+	return &team, nil
+}
+
+func inheritSettings(dst *models.TeamSettings, parent models.TeamSettings) {
+	if dst.ReviewerStrategy == nil {
+		dst.ReviewerStrategy = parent.ReviewerStrategy
+	}
+	if dst.MinApprovals == nil {
+		dst.MinApprovals = parent.MinApprovals
+	}
+	if dst.BlockOnChangesRequested == nil {
+		dst.BlockOnChangesRequested = parent.BlockOnChangesRequested
+	}
+	if dst.ForbidSelfApproval == nil {
+		dst.ForbidSelfApproval = parent.ForbidSelfApproval
+	}
+	if dst.OnReviewerDeactivation == nil {
+		dst.OnReviewerDeactivation = parent.OnReviewerDeactivation
+	}
+	if dst.DefaultReviewers == nil {
+		dst.DefaultReviewers = parent.DefaultReviewers
+	}
+	if dst.MinReviewers == nil {
+		dst.MinReviewers = parent.MinReviewers
+	}
+	if dst.MaxReviewers == nil {
+		dst.MaxReviewers = parent.MaxReviewers
+	}
+	if dst.MinSeniorReviewers == nil {
+		dst.MinSeniorReviewers = parent.MinSeniorReviewers
+	}
+	if dst.JuniorNeedsLead == nil {
+		dst.JuniorNeedsLead = parent.JuniorNeedsLead
+	}
+}
+
+// checkSubtreeSettings fails when the effective reviewer settings of the
+// team or of a team below it contradict each other, e.g. a sub-team's own
+// max_reviewers under a min_reviewers it inherits.
+func (s *teamService) checkSubtreeSettings(ctx context.Context, name string) error {
+	root, err := effectiveTeam(ctx, s.teams, name)
+	if err != nil {
+		return err
+	}
+	all, err := s.teams.List(ctx)
+	if err != nil {
+		return err
+	}
+	children := make(map[string][]models.Team)
+	for _, t := range all {
+		if t.ParentTeam != nil {
+			children[*t.ParentTeam] = append(children[*t.ParentTeam], t)
+		}
+	}
+
+	queue := []models.Team{*root}
+	seen := make(map[string]bool)
+	for len(queue) > 0 {
+		team := queue[0]
+		queue = queue[1:]
+		if seen[team.TeamName] {
+			continue
+		}
+		seen[team.TeamName] = true
+		if err := checkReviewerSettings(&team); err != nil {
+			if team.TeamName == name {
+				return err
+			}
+			return apperr.Wrap(err, apperr.CodeOf(err), fmt.Sprintf("sub-team %s: %s", team.TeamName, apperr.MessageOf(err)))
+		}
+		for _, child := range children[team.TeamName] {
+			inheritSettings(&child.TeamSettings, team.TeamSettings)
+			queue = append(queue, child)
+		}
+	}
+	return nil
+}
+
+// checkReviewerSettings validates the effective reviewer counts of a team.
+func checkReviewerSettings(team *models.Team) error {
+	bounds := reviewerBounds(team)
+	if !validReviewerBounds(bounds) {
+		return ErrInvalidReviewerBounds
+	}
+	if n := team.MinSeniorReviewers; n != nil && (*n < 0 || *n > bounds.Max) {
+		return ErrInvalidMinSeniors
+	}
+	return nil
+}
+
+// SetParent moves the team under parent, or makes it a root team when parent is nil.
+func (s *teamService) SetParent(ctx context.Context, name string, parent *string) (*models.Team, error) {
+	var team *models.Team
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if _, err := s.teams.GetByName(ctx, name); err != nil {
+			return err
+		}
+		if parent != nil {
+			ancestors, err := s.teams.ListAncestors(ctx, *parent)
+			if err != nil {
+				return err
+			}
+			for _, a := range ancestors {
+				if a.TeamName == name {
+					return ErrTeamCycle
+				}
+			}
+		}
+		if err := s.teams.SetParent(ctx, name, parent); err != nil {
+			return err
+		}
+		if err := s.checkSubtreeSettings(ctx, name); err != nil {
+			return err
+		}
+		var err error
+		team, err = s.teams.GetByName(ctx, name)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return team, nil
+}
+
+// GetTeamTree returns the team with its members and all sub-teams below it.
+func (s *teamService) GetTeamTree(ctx context.Context, name string) (*models.TeamNode, error) {
+	all, err := s.teams.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	children := make(map[string][]string)
+	found := false
+	for _, t := range all {
+		if t.TeamName == name {
+			found = true
+		}
+		if t.ParentTeam != nil {
+			children[*t.ParentTeam] = append(children[*t.ParentTeam], t.TeamName)
+		}
+	}
+	if !found {
+		return nil, repository.ErrTeamNotFound
+	}
+	return s.teamNode(ctx, name, children, make(map[string]bool))
+}
+
+func (s *teamService) teamNode(ctx context.Context, name string, children map[string][]string, seen map[string]bool) (*models.TeamNode, error) {
+	seen[name] = true
+	details, err := s.GetTeamDetails(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	node := &models.TeamNode{TeamDetails: *details, Children: make([]models.TeamNode, 0, len(children[name]))}
+	for _, child := range children[name] {
+		if seen[child] {
+			continue
+		}
+		c, err := s.teamNode(ctx, child, children, seen)
+		if err != nil {
+			return nil, err
+		}
+		node.Children = append(node.Children, *c)
+	}
+	return node, nil
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"pr-reviewer/internal/apperr"
+	"pr-reviewer/internal/models"
+)
+
+var ErrPRNotOpen = apperr.New(apperr.CodeInvalidState, "pull request is not open")
+
+// prTransitions is the pull request state machine: the statuses each
+// status may move to. MERGED is final.
+var prTransitions = map[models.PRStatus][]models.PRStatus{
+	models.PRStatusDraft:  {models.PRStatusOpen, models.PRStatusClosed},
+	models.PRStatusOpen:   {models.PRStatusMerged, models.PRStatusClosed},
+	models.PRStatusClosed: {models.PRStatusOpen},
+}
+
+func checkTransition(from, to models.PRStatus) error {
+	for _, s := range prTransitions[from] {
+		if s == to {
+			return nil
+		}
+	}
+	return apperr.New(apperr.CodeInvalidState, fmt.Sprintf("cannot move pull request from %s to %s", from, to))
+}
+
+// checkOpen rejects changes to reviewers of a pull request that is not OPEN.
+func checkOpen(pr *models.PullRequest) error {
+	switch pr.Status {
+	case models.PRStatusOpen:
+		return nil
+	case models.PRStatusMerged:
+		return ErrCannotModifyMerged
+	default:
+		return ErrPRNotOpen
+	}
+}
+
+// TransitionInput moves a pull request to another status. ActorID is
+// recorded in the history; Strategy is used when reviewers get assigned.
+type TransitionInput struct {
+	PullRequestID string
+	ActorID       string
+	Strategy      string
+}
+
+// newEvent builds a history entry; details is marshaled to JSON when set.
+func newEvent(prID string, typ models.PREventType, actorID string, details any) (*models.PREvent, error) {
+	event := &models.PREvent{PullRequestID: prID, Type: typ}
+	if actorID != "" {
+		event.ActorID = &actorID
+	}
+	if details != nil {
+		raw, err := json.Marshal(details)
+		if err != nil {
+			return nil, err
+		}
+		event.Details = raw
+	}
+	return event, nil
+}
+
+func (s *prService) MarkReady(ctx context.Context, in TransitionInput) (*models.PullRequest, *Assignment, error) {
+	return s.transition(ctx, in, models.PRStatusDraft, models.PRStatusOpen, models.EventReadyForReview)
+}
+
+func (s *prService) ReopenPR(ctx context.Context, in TransitionInput) (*models.PullRequest, *Assignment, error) {
+	return s.transition(ctx, in, models.PRStatusClosed, models.PRStatusOpen, models.EventReopened)
+}
+
+func (s *prService) ClosePR(ctx context.Context, in TransitionInput) (*models.PullRequest, error) {
+	pr, _, err := s.transition(ctx, in, "", models.PRStatusClosed, models.EventClosed)
+	if err != nil {
+		return nil, err
+	}
+	// reviewers of a closed PR have capacity again
+	s.DrainReviewQueue(ctx)
+	return pr, nil
+}
+
+// transition moves the PR to status to, recording event. A non empty from
+// restricts the source status, so that e.g. reopen does not apply to drafts.
+// Reviewers are assigned whenever the PR becomes OPEN.
+func (s *prService) transition(ctx context.Context, in TransitionInput, from, to models.PRStatus, event models.PREventType) (*models.PullRequest, *Assignment, error) {
+	var pr *models.PullRequest
+	var assignment *Assignment
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		pr, err = s.prRepo.GetByIDForUpdate(ctx, in.PullRequestID)
+		if err != nil {
+			return err
+		}
+		if from != "" && pr.Status != from {
+			return apperr.New(apperr.CodeInvalidState, fmt.Sprintf("pull request is %s, expected %s", pr.Status, from))
+		}
+		if err := checkTransition(pr.Status, to); err != nil {
+			return err
+		}
+
+		if err := s.prRepo.SetStatus(ctx, pr.PullRequestID, to); err != nil {
+			return err
+		}
+		pr.Status = to
+		if to == models.PRStatusOpen {
+			if assignment, err = s.assignReviewers(ctx, pr, in.Strategy); err != nil {
+				return err
+			}
+		}
+
+		e, err := newEvent(pr.PullRequestID, event, in.ActorID, nil)
+		if err != nil {
+			return err
+		}
+		if err := s.eventRepo.Create(ctx, e); err != nil {
+			return err
+		}
+
+		pr, err = s.prRepo.GetByID(ctx, pr.PullRequestID)
+		if err != nil {
+			return err
+		}
+		pr, _, err = s.withReviewers(ctx, pr)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return pr, assignment, nil
+}
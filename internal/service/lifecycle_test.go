@@ -0,0 +1,103 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"pr-reviewer/internal/apperr"
+	"pr-reviewer/internal/models"
+)
+
+func TestCheckTransition(t *testing.T) {
+	const (
+		draft  = models.PRStatusDraft
+		open   = models.PRStatusOpen
+		merged = models.PRStatusMerged
+		closed = models.PRStatusClosed
+	)
+	tests := []struct {
+		from, to models.PRStatus
+		allowed  bool
+	}{
+		{draft, open, true},
+		{draft, closed, true},
+		{draft, merged, false},
+		{draft, draft, false},
+		{open, merged, true},
+		{open, closed, true},
+		{open, draft, false},
+		{open, open, false},
+		{closed, open, true},
+		{closed, merged, false},
+		{closed, draft, false},
+		{merged, open, false},
+		{merged, closed, false},
+		{merged, draft, false},
+		{merged, merged, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			err := checkTransition(tt.from, tt.to)
+			if tt.allowed && err != nil {
+				t.Errorf("checkTransition: %v, want it allowed", err)
+			}
+			if !tt.allowed && apperr.CodeOf(err) != apperr.CodeInvalidState {
+				t.Errorf("checkTransition = %v, want INVALID_STATE", err)
+			}
+		})
+	}
+}
+
+func TestCheckOpen(t *testing.T) {
+	tests := []struct {
+		status models.PRStatus
+		want   error
+	}{
+		{models.PRStatusOpen, nil},
+		{models.PRStatusMerged, ErrCannotModifyMerged},
+		{models.PRStatusDraft, ErrPRNotOpen},
+		{models.PRStatusClosed, ErrPRNotOpen},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			if err := checkOpen(&models.PullRequest{Status: tt.status}); !errors.Is(err, tt.want) {
+				t.Errorf("checkOpen = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		actorID string
+		details any
+		actor   bool
+		json    string
+	}{
+		{name: "bare"},
+		{name: "with actor", actorID: "a", actor: true},
+		{
+			name:    "with details",
+			details: models.ReviewerChange{PullRequestID: "pr-1", OldReviewerID: "a", NewReviewerID: "b"},
+			json:    `{"pull_request_id":"pr-1","old_reviewer_id":"a","new_reviewer_id":"b"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := newEvent("pr-1", models.EventClosed, tt.actorID, tt.details)
+			if err != nil {
+				t.Fatalf("newEvent: %v", err)
+			}
+			if e.PullRequestID != "pr-1" || e.Type != models.EventClosed {
+				t.Errorf("newEvent = %+v", e)
+			}
+			if (e.ActorID != nil) != tt.actor || (tt.actor && *e.ActorID != tt.actorID) {
+				t.Errorf("actor = %v, want %q", e.ActorID, tt.actorID)
+			}
+			if string(e.Details) != tt.json {
+				t.Errorf("details = %s, want %s", e.Details, tt.json)
+			}
+		})
+	}
+}
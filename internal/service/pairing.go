@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"pr-reviewer/internal/apperr"
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/repository"
+)
+
+const StrategyLeastPaired = "least_paired"
+
+// pairingWindow is how many half-lives of history the pairing selector
+// looks at; older pairings weigh less than 1/16 and are ignored.
+const pairingWindow = 4
+
+var ErrInvalidWindow = apperr.New(apperr.CodeValidation, "since must be before until")
+
+// leastPairedSelector prefers candidates who reviewed the author least
+// recently and least often. Every past pairing adds a penalty that halves
+// with each halfLife of age; ties are broken by open review load.
+type leastPairedSelector struct {
+	prs      repository.PRRepository
+	halfLife time.Duration
+}
+
+func NewLeastPairedSelector(prs repository.PRRepository, halfLife time.Duration) ReviewerSelector {
+	return &leastPairedSelector{prs: prs, halfLife: halfLife}
+}
+
+func (s *leastPairedSelector) Name() string { return StrategyLeastPaired }
+
+func (s *leastPairedSelector) Select(ctx context.Context, req SelectionRequest) ([]models.Candidate, error) {
+	if req.Count <= 0 || len(req.Candidates) == 0 {
+		return nil, nil
+	}
+	ids := make([]string, 0, len(req.Candidates))
+	for _, c := range req.Candidates {
+		ids = append(ids, c.UserID)
+	}
+	now := time.Now()
+	history, err := s.prs.ListPairings(ctx, req.AuthorID, ids, now.Add(-pairingWindow*s.halfLife))
+	if err != nil {
+		return nil, err
+	}
+
+	penalty := make(map[string]float64, len(history))
+	for id, times := range history {
+		for _, at := range times {
+			penalty[id] += math.Exp2(-float64(now.Sub(at)) / float64(s.halfLife))
+		}
+	}
+	sorted := sortByLoad(req.Candidates)
+	sort.SliceStable(sorted, func(i, j int) bool { return penalty[sorted[i].UserID] < penalty[sorted[j].UserID] })
+	return limit(sorted, req.Count), nil
+}
+
+// PairingMatrix counts how often each reviewer was assigned to pull requests
+// of each author of the team, over PRs created in [since, until).
+func (s *teamService) PairingMatrix(ctx context.Context, name string, since, until time.Time) (*models.PairingMatrix, error) {
+	if !since.Before(until) {
+		return nil, ErrInvalidWindow
+	}
+	if _, err := s.teams.GetByName(ctx, name); err != nil {
+		return nil, err
+	}
+	pairs, err := s.prs.CountPairings(ctx, name, since, until)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &models.PairingMatrix{TeamName: name, Since: since, Until: until, Authors: []string{}, Reviewers: []string{}}
+	authorIdx := make(map[string]int)
+	reviewerIdx := make(map[string]int)
+	for _, p := range pairs {
+		if _, ok := authorIdx[p.AuthorID]; !ok {
+			authorIdx[p.AuthorID] = 0
+			m.Authors = append(m.Authors, p.AuthorID)
+		}
+		if _, ok := reviewerIdx[p.ReviewerID]; !ok {
+			reviewerIdx[p.ReviewerID] = 0
+			m.Reviewers = append(m.Reviewers, p.ReviewerID)
+		}
+	}
+	sort.Strings(m.Authors)
+	sort.Strings(m.Reviewers)
+	for i, id := range m.Authors {
+		authorIdx[id] = i
+	}
+	for j, id := range m.Reviewers {
+		reviewerIdx[id] = j
+	}
+
+	m.Counts = make([][]int, len(m.Authors))
+	for i := range m.Counts {
+		m.Counts[i] = make([]int, len(m.Reviewers))
+	}
+	for _, p := range pairs {
+		m.Counts[authorIdx[p.AuthorID]][reviewerIdx[p.ReviewerID]] = p.Count
+	}
+	return m, nil
+}
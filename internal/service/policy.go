@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+
+	"pr-reviewer/internal/apperr"
+	"pr-reviewer/internal/models"
+)
+
+var (
+	ErrNotEnoughApprovals  = apperr.New(apperr.CodeMergeBlocked, "not enough approvals to merge")
+	ErrChangesRequested    = apperr.New(apperr.CodeMergeBlocked, "a reviewer requested changes")
+	ErrSelfApproval        = apperr.New(apperr.CodeValidation, "author cannot approve own pull request")
+	ErrForceNotAllowed     = apperr.New(apperr.CodeForbidden, "only admins can force a merge")
+	ErrInvalidMinApprovals = apperr.New(apperr.CodeValidation, "min_approvals must not be negative")
+	ErrForceReasonRequired = apperr.New(apperr.CodeValidation, "reason is required to force a merge")
+)
+
+// defaultMergePolicy applies to teams that did not configure a rule.
+var defaultMergePolicy = models.MergePolicy{
+	MinApprovals:            0,
+	BlockOnChangesRequested: false,
+	ForbidSelfApproval:      true,
+}
+
+// mergePolicy returns the effective merge policy of a team.
+func mergePolicy(team *models.Team) models.MergePolicy {
+	p := defaultMergePolicy
+	if team == nil {
+		return p
+	}
+	if team.MinApprovals != nil {
+		p.MinApprovals = *team.MinApprovals
+	}
+	if team.BlockOnChangesRequested != nil {
+		p.BlockOnChangesRequested = *team.BlockOnChangesRequested
+	}
+	if team.ForbidSelfApproval != nil {
+		p.ForbidSelfApproval = *team.ForbidSelfApproval
+	}
+	return p
+}
+
+// MergeInput is a merge request. Force skips the merge policy and is
+// only allowed for admins; forced merges are recorded in the PR history.
+type MergeInput struct {
+	PullRequestID string
+	ActorID       string
+	Force         bool
+	Reason        string
+}
+
+// checkMergePolicy evaluates the latest verdict of every currently assigned
+// reviewer against the policy of the pull request's team. Verdicts of
+// reviewers who were removed or replaced do not count.
+func (s *prService) checkMergePolicy(ctx context.Context, pr *models.PullRequest) error {
+	team, err := effectiveTeam(ctx, s.teamRepo, pr.TeamName)
+	if err != nil {
+		return err
+	}
+	policy := mergePolicy(team)
+
+	reviewers, err := s.prRepo.ListReviewers(ctx, pr.PullRequestID)
+	if err != nil {
+		return err
+	}
+	assigned := make(map[string]bool, len(reviewers))
+	for _, u := range reviewers {
+		assigned[u.UserID] = true
+	}
+	reviews, err := s.reviewRepo.ListByPR(ctx, pr.PullRequestID)
+	if err != nil {
+		return err
+	}
+	// reviews are ordered by time, so later verdicts win
+	latest := make(map[string]models.ReviewVerdict, len(reviews))
+	for _, rv := range reviews {
+		if assigned[rv.ReviewerID] {
+			latest[rv.ReviewerID] = rv.Verdict
+		}
+	}
+
+	approvals := 0
+	for reviewerID, verdict := range latest {
+		switch verdict {
+		case models.VerdictApproved:
+			if policy.ForbidSelfApproval && reviewerID == pr.AuthorID {
+				continue
+			}
+			approvals++
+		case models.VerdictChangesRequested:
+			if policy.BlockOnChangesRequested {
+				return ErrChangesRequested
+			}
+		}
+	}
+	if approvals < policy.MinApprovals {
+		return ErrNotEnoughApprovals
+	}
+	return nil
+}
+
+// authorizeForce checks that the actor may bypass the merge policy.
+func (s *prService) authorizeForce(ctx context.Context, in MergeInput) error {
+	if in.ActorID == "" {
+		return ErrForceNotAllowed
+	}
+	if in.Reason == "" {
+		return ErrForceReasonRequired
+	}
+	actor, err := s.userRepo.GetByID(ctx, in.ActorID)
+	if err != nil {
+		return err
+	}
+	if !actor.IsAdmin {
+		return ErrForceNotAllowed
+	}
+	return nil
+}
+
+// mergeEvent builds the history entry of a merge. violation is the policy
+// check result that a forced merge overrode, if any.
+func mergeEvent(in MergeInput, violation error) (*models.PREvent, error) {
+	if !in.Force {
+		return newEvent(in.PullRequestID, models.EventMerged, in.ActorID, nil)
+	}
+	details := struct {
+		Reason     string `json:"reason"`
+		Overridden string `json:"overridden,omitempty"`
+	}{Reason: in.Reason}
+	if violation != nil {
+		details.Overridden = apperr.MessageOf(violation)
+	}
+	return newEvent(in.PullRequestID, models.EventForceMerged, in.ActorID, details)
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/repository"
+)
+
+// teamChain serves a team and its ancestors from memory.
+type teamChain struct {
+	repository.TeamRepository
+	chain []models.Team
+}
+
+func (r *teamChain) ListAncestors(context.Context, string) ([]models.Team, error) {
+	return r.chain, nil
+}
+
+// assignedReviewers serves the reviewers of every pull request from memory.
+type assignedReviewers struct {
+	repository.PRRepository
+	reviewers []models.User
+}
+
+func (r *assignedReviewers) ListReviewers(context.Context, string) ([]models.User, error) {
+	return r.reviewers, nil
+}
+
+// reviewLog serves the reviews of every pull request from memory, oldest first.
+type reviewLog struct {
+	repository.ReviewRepository
+	reviews []models.Review
+}
+
+func (r *reviewLog) ListByPR(context.Context, string) ([]models.Review, error) {
+	return r.reviews, nil
+}
+
+func boolPtr(b bool) *bool    { return &b }
+func intPtr(n int) *int       { return &n }
+func strPtr(s string) *string { return &s }
+
+func TestMergePolicy(t *testing.T) {
+	tests := []struct {
+		name string
+		team *models.Team
+		want models.MergePolicy
+	}{
+		{name: "no team", want: defaultMergePolicy},
+		{name: "nothing set", team: &models.Team{}, want: defaultMergePolicy},
+		{
+			name: "everything set",
+			team: &models.Team{TeamSettings: models.TeamSettings{
+				MinApprovals:            intPtr(2),
+				BlockOnChangesRequested: boolPtr(true),
+				ForbidSelfApproval:      boolPtr(false),
+			}},
+			want: models.MergePolicy{MinApprovals: 2, BlockOnChangesRequested: true, ForbidSelfApproval: false},
+		},
+		{
+			name: "only approvals set",
+			team: &models.Team{TeamSettings: models.TeamSettings{MinApprovals: intPtr(1)}},
+			want: models.MergePolicy{MinApprovals: 1, BlockOnChangesRequested: false, ForbidSelfApproval: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergePolicy(tt.team); got != tt.want {
+				t.Errorf("mergePolicy = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckMergePolicy(t *testing.T) {
+	review := func(reviewer string, v models.ReviewVerdict) models.Review {
+		return models.Review{ReviewerID: reviewer, Verdict: v}
+	}
+	strict := models.TeamSettings{MinApprovals: intPtr(1), BlockOnChangesRequested: boolPtr(true)}
+	tests := []struct {
+		name      string
+		settings  models.TeamSettings
+		parent    models.TeamSettings
+		reviewers []string
+		reviews   []models.Review
+		want      error
+	}{
+		{name: "default policy needs nothing", reviewers: []string{"a"}},
+		{
+			name:      "not enough approvals",
+			settings:  models.TeamSettings{MinApprovals: intPtr(2)},
+			reviewers: []string{"a", "b"},
+			reviews:   []models.Review{review("a", models.VerdictApproved), review("b", models.VerdictCommented)},
+			want:      ErrNotEnoughApprovals,
+		},
+		{
+			name:      "inherited approvals",
+			parent:    models.TeamSettings{MinApprovals: intPtr(1)},
+			reviewers: []string{"a"},
+			want:      ErrNotEnoughApprovals,
+		},
+		{
+			name:      "changes requested block",
+			settings:  strict,
+			reviewers: []string{"a", "b"},
+			reviews:   []models.Review{review("a", models.VerdictApproved), review("b", models.VerdictChangesRequested)},
+			want:      ErrChangesRequested,
+		},
+		{
+			name:      "changes requested without blocking",
+			settings:  models.TeamSettings{MinApprovals: intPtr(1)},
+			reviewers: []string{"a", "b"},
+			reviews:   []models.Review{review("a", models.VerdictApproved), review("b", models.VerdictChangesRequested)},
+		},
+		{
+			name:      "later verdict wins",
+			settings:  strict,
+			reviewers: []string{"a"},
+			reviews:   []models.Review{review("a", models.VerdictChangesRequested), review("a", models.VerdictApproved)},
+		},
+		{
+			name:      "approval taken back",
+			settings:  strict,
+			reviewers: []string{"a"},
+			reviews:   []models.Review{review("a", models.VerdictApproved), review("a", models.VerdictChangesRequested)},
+			want:      ErrChangesRequested,
+		},
+		{
+			name:      "removed reviewers do not count",
+			settings:  strict,
+			reviewers: []string{"a"},
+			reviews:   []models.Review{review("x", models.VerdictApproved), review("y", models.VerdictChangesRequested)},
+			want:      ErrNotEnoughApprovals,
+		},
+		{
+			name:      "self approval forbidden by default",
+			settings:  models.TeamSettings{MinApprovals: intPtr(1)},
+			reviewers: []string{"author"},
+			reviews:   []models.Review{review("author", models.VerdictApproved)},
+			want:      ErrNotEnoughApprovals,
+		},
+		{
+			name:      "self approval allowed",
+			settings:  models.TeamSettings{MinApprovals: intPtr(1), ForbidSelfApproval: boolPtr(false)},
+			reviewers: []string{"author"},
+			reviews:   []models.Review{review("author", models.VerdictApproved)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prs := &assignedReviewers{}
+			for _, id := range tt.reviewers {
+				prs.reviewers = append(prs.reviewers, models.User{UserID: id})
+			}
+			s := &prService{
+				teamRepo: &teamChain{chain: []models.Team{
+					{TeamName: "core", TeamSettings: tt.settings},
+					{TeamName: "org", TeamSettings: tt.parent},
+				}},
+				prRepo:     prs,
+				reviewRepo: &reviewLog{reviews: tt.reviews},
+			}
+			pr := &models.PullRequest{PullRequestID: "pr-1", AuthorID: "author", TeamName: "core"}
+			if err := s.checkMergePolicy(context.Background(), pr); !errors.Is(err, tt.want) {
+				t.Errorf("checkMergePolicy = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeEvent(t *testing.T) {
+	tests := []struct {
+		name      string
+		in        MergeInput
+		violation error
+		typ       models.PREventType
+		details   string
+	}{
+		{name: "merge", in: MergeInput{PullRequestID: "pr-1", ActorID: "a"}, typ: models.EventMerged},
+		{
+			name:    "force within the policy",
+			in:      MergeInput{PullRequestID: "pr-1", ActorID: "a", Force: true, Reason: "hotfix"},
+			typ:     models.EventForceMerged,
+			details: `{"reason":"hotfix"}`,
+		},
+		{
+			name:      "force over a violation",
+			in:        MergeInput{PullRequestID: "pr-1", ActorID: "a", Force: true, Reason: "hotfix"},
+			violation: ErrNotEnoughApprovals,
+			typ:       models.EventForceMerged,
+			details:   `{"reason":"hotfix","overridden":"not enough approvals to merge"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := mergeEvent(tt.in, tt.violation)
+			if err != nil {
+				t.Fatalf("mergeEvent: %v", err)
+			}
+			if e.Type != tt.typ || e.PullRequestID != tt.in.PullRequestID || e.ActorID == nil || *e.ActorID != tt.in.ActorID {
+				t.Errorf("mergeEvent = %+v", e)
+			}
+			if string(e.Details) != tt.details {
+				t.Errorf("details = %s, want %s", e.Details, tt.details)
+			}
+		})
+	}
+}
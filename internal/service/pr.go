@@ -3,173 +3,596 @@ package service
 import (
 	"context"
 	"errors"
+	"pr-reviewer/internal/apperr"
 	"pr-reviewer/internal/models"
 	"pr-reviewer/internal/repository"
+	"pr-reviewer/internal/store"
+	"slices"
 
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 )
 
 var (
-	ErrNoAvailableReviewers = errors.New("no available reviewers")
-	ErrReviewerNotInPR      = errors.New("reviewer not assigned")
-	ErrCannotModifyMerged   = errors.New("cannot modify merged PR")
+	ErrNoAvailableReviewers = apperr.New(apperr.CodeNoCandidate, "no active replacement candidate in team")
+	ErrReviewerNotInPR      = apperr.New(apperr.CodeNotAssigned, "reviewer is not assigned to this PR")
+	ErrCannotModifyMerged   = apperr.New(apperr.CodePRMerged, "cannot modify merged PR")
+	ErrAllAtCapacity        = apperr.New(apperr.CodeNoCandidate, "all candidates are at review capacity")
+	ErrPRExists             = apperr.New(apperr.CodePRExists, "PR id already exists")
+	ErrAuthorHasNoTeam      = apperr.New(apperr.CodeNotFound, "author has no team")
+	ErrAmbiguousTeam        = apperr.New(apperr.CodeValidation, "author belongs to several teams, team_name required")
+	ErrAuthorNotInTeam      = apperr.New(apperr.CodeValidation, "author is not a member of the team")
+	ErrInvalidVerdict       = apperr.New(apperr.CodeValidation, "verdict must be one of APPROVED, CHANGES_REQUESTED, COMMENTED")
 )
 
+// CreatePRInput is the data needed to open a pull request.
+// PullRequestID is generated when empty. Strategy overrides the team
+// reviewer selection strategy when set. ReviewerCount overrides the team
+// default within the team bounds. Drafts get no reviewers until they are
+// marked ready. TeamName picks the author's team the PR belongs to; it may
+// be omitted when the author is in a single team. Paths lists the changed
+// files; their code owners are preferred as reviewers. RequiredSkills are
+// the areas that need at least one reviewer having them.
+type CreatePRInput struct {
+	PullRequestID  string
+	Name           string
+	AuthorID       string
+	TeamName       string
+	Strategy       string
+	ReviewerCount  *int
+	Draft          bool
+	Paths          []string
+	RequiredSkills []string
+}
+
 type PRService interface {
-	CreatePR(ctx context.Context, name string, authorID string) (*models.PullRequest, []models.User, error)
-	ReassignReviewer(ctx context.Context, prID string, oldReviewerID string) (*models.User, error)
-	MergePR(ctx context.Context, prID string) (*models.PullRequest, error)
-	GetPR(ctx context.Context, id string) (*models.PullRequest, []models.User, error)
-	ListByReviewer(ctx context.Context, reviewerID string) ([]models.PullRequest, error)
+	// CreatePR opens a pull request and assigns reviewers. Creation is idempotent:
+	// retrying with the same id, name and author returns the stored PR with a
+	// nil Assignment; the same id with different data fails with ErrPRExists.
+	CreatePR(ctx context.Context, in CreatePRInput) (*models.PullRequest, *Assignment, error)
+	ReassignReviewer(ctx context.Context, prID string, oldReviewerID string, strategy string) (*models.PullRequest, *Assignment, error)
+	// MergePR merges the pull request once the team merge policy is satisfied.
+	// Merging an already merged PR is a no-op.
+	MergePR(ctx context.Context, in MergeInput) (*models.PullRequest, error)
+	GetPR(ctx context.Context, id string) (*models.PullRequestDetails, error)
+	ListByReviewer(ctx context.Context, reviewerID string) ([]models.ReviewAssignment, error)
+	SubmitReview(ctx context.Context, prID string, reviewerID string, verdict models.ReviewVerdict, message *string) (*models.Review, error)
+	// MarkReady moves a DRAFT to OPEN and assigns its reviewers.
+	MarkReady(ctx context.Context, in TransitionInput) (*models.PullRequest, *Assignment, error)
+	// ClosePR abandons a DRAFT or OPEN pull request without merging it.
+	ClosePR(ctx context.Context, in TransitionInput) (*models.PullRequest, error)
+	// ReopenPR moves a CLOSED pull request back to OPEN, filling free reviewer slots.
+	ReopenPR(ctx context.Context, in TransitionInput) (*models.PullRequest, *Assignment, error)
+	// AddReviewer adds an extra reviewer, raising the PR's reviewer count.
+	AddReviewer(ctx context.Context, in AddReviewerInput) (*models.PullRequest, *Assignment, error)
+	// RemoveReviewer drops a reviewer without replacement.
+	RemoveReviewer(ctx context.Context, prID string, reviewerID string, actorID string) (*models.PullRequest, error)
+	// DeactivateUsers deactivates users and moves their OPEN reviews to other
+	// active candidates in one transaction.
+	DeactivateUsers(ctx context.Context, in DeactivateInput) (*DeactivationReport, error)
+	// HandOffReviews applies the team deactivation setting to the OPEN reviews
+	// of users who are already inactive, in all teams or only in teamName.
+	HandOffReviews(ctx context.Context, userIDs []string, teamName string) (*DeactivationReport, error)
+	// DrainReviewQueue assigns queued reviewer slots to users with free
+	// capacity. Call it after a change that frees capacity was committed.
+	DrainReviewQueue(ctx context.Context)
 }
 
 type prService struct {
-	prRepo   repository.PRRepository
-	userRepo repository.UserRepository
-	teamRepo repository.TeamRepository
+	tx            store.Transactor
+	prRepo        repository.PRRepository
+	userRepo      repository.UserRepository
+	teamRepo      repository.TeamRepository
+	reviewRepo    repository.ReviewRepository
+	eventRepo     repository.EventRepository
+	absenceRepo   repository.AbsenceRepository
+	exclusionRepo repository.ExclusionRepository
+	selectors     *SelectorRegistry
+	log           *zap.Logger
 }
 
-func NewPRService(pr repository.PRRepository, users repository.UserRepository, teams repository.TeamRepository) PRService {
-	return &prService{prRepo: pr, userRepo: users, teamRepo: teams}
+func NewPRService(
+	tx store.Transactor,
+	pr repository.PRRepository,
+	users repository.UserRepository,
+	teams repository.TeamRepository,
+	reviews repository.ReviewRepository,
+	events repository.EventRepository,
+	absences repository.AbsenceRepository,
+	exclusions repository.ExclusionRepository,
+	selectors *SelectorRegistry,
+	log *zap.Logger,
+) PRService {
+	return &prService{
+		tx:            tx,
+		prRepo:        pr,
+		userRepo:      users,
+		teamRepo:      teams,
+		reviewRepo:    reviews,
+		eventRepo:     events,
+		absenceRepo:   absences,
+		exclusionRepo: exclusions,
+		selectors:     selectors,
+		log:           log,
+	}
 }
 
-func (s *prService) CreatePR(ctx context.Context, name string, authorID string) (*models.PullRequest, []models.User, error) {
-	author, err := s.userRepo.GetByID(ctx, authorID)
+// withReviewers fills AssignedReviewers of the pull request.
+func (s *prService) withReviewers(ctx context.Context, pr *models.PullRequest) (*models.PullRequest, []models.User, error) {
+	revs, err := s.prRepo.ListReviewers(ctx, pr.PullRequestID)
 	if err != nil {
 		return nil, nil, err
 	}
-	if author.TeamName == nil {
-		return nil, nil, errors.New("author has no team")
+	pr.AssignedReviewers = make([]string, 0, len(revs))
+	for _, r := range revs {
+		pr.AssignedReviewers = append(pr.AssignedReviewers, r.UserID)
 	}
+	return pr, revs, nil
+}
 
-	teamUsers, err := s.userRepo.ListUsersByTeam(ctx, *author.TeamName)
+// existingPR returns the stored pull request when in is a retry of its creation,
+// nil when the id is free and ErrPRExists when the id is taken by a different PR.
+func (s *prService) existingPR(ctx context.Context, in CreatePRInput) (*models.PullRequest, error) {
+	pr, err := s.prRepo.GetByID(ctx, in.PullRequestID)
+	if errors.Is(err, repository.ErrPRNotFound) {
+		return nil, nil
+	}
 	if err != nil {
-		return nil, nil, err
+		return nil, err
+	}
+	if pr.PullRequestName != in.Name || pr.AuthorID != in.AuthorID || (in.TeamName != "" && pr.TeamName != in.TeamName) {
+		return nil, ErrPRExists
 	}
+	pr, _, err = s.withReviewers(ctx, pr)
+	return pr, err
+}
 
-	// choose reviewers
-	var reviewers []models.User
-	for _, u := range teamUsers {
-		if u.UserID == authorID {
-			continue
-		}
-		if u.IsActive {
-			reviewers = append(reviewers, u)
+func (s *prService) CreatePR(ctx context.Context, in CreatePRInput) (*models.PullRequest, *Assignment, error) {
+	if in.PullRequestID == "" {
+		in.PullRequestID = uuid.New().String()
+	} else if existing, err := s.existingPR(ctx, in); err != nil || existing != nil {
+		return existing, nil, err
+	}
+
+	var pr *models.PullRequest
+	var assignment *Assignment
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		pr, assignment, err = s.createPR(ctx, in)
+		return err
+	})
+	if apperr.CodeOf(err) == apperr.CodeAlreadyExists {
+		// lost a race with a concurrent create of the same id
+		existing, err := s.existingPR(ctx, in)
+		if err == nil && existing == nil {
+			err = ErrPRExists
 		}
-		if len(reviewers) >= 2 {
-			break
+		return existing, nil, err
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return pr, assignment, nil
+}
+
+func (s *prService) createPR(ctx context.Context, in CreatePRInput) (*models.PullRequest, *Assignment, error) {
+	paths, err := cleanPaths(in.Paths)
+	if err != nil {
+		return nil, nil, err
+	}
+	skills, err := normalizeSkills(in.RequiredSkills)
+	if err != nil {
+		return nil, nil, err
+	}
+	author, err := s.userRepo.GetByID(ctx, in.AuthorID)
+	if err != nil {
+		return nil, nil, err
+	}
+	teamName, err := authorTeam(author, in.TeamName)
+	if err != nil {
+		return nil, nil, err
+	}
+	team, err := effectiveTeam(ctx, s.teamRepo, teamName)
+	if err != nil {
+		return nil, nil, err
+	}
+	bounds := reviewerBounds(team)
+	count := bounds.Default
+	if in.ReviewerCount != nil {
+		count = *in.ReviewerCount
+		if count < bounds.Min || count > bounds.Max {
+			return nil, nil, reviewerCountError(count, bounds)
 		}
 	}
 
-	// create PR
 	pr := &models.PullRequest{
-		PullRequestID:   uuid.New().String(),
-		PullRequestName: name,
-		AuthorID:        authorID,
-		TeamName:        *author.TeamName,
-		Status:          "OPEN",
+		PullRequestID:   in.PullRequestID,
+		PullRequestName: in.Name,
+		AuthorID:        in.AuthorID,
+		TeamName:        teamName,
+		Status:          models.PRStatusOpen,
+		ReviewerCount:   count,
+	}
+	if in.Draft {
+		pr.Status = models.PRStatusDraft
 	}
 
-	err = s.prRepo.Create(ctx, pr)
-	if err != nil {
+	if err := s.prRepo.Create(ctx, pr); err != nil {
 		return nil, nil, err
 	}
-
-	// assign reviewers
-	for _, r := range reviewers {
-		_ = s.prRepo.AddReviewer(ctx, pr.PullRequestID, r.UserID)
+	if len(paths) > 0 {
+		if err := s.prRepo.AddFiles(ctx, pr.PullRequestID, paths); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(skills) > 0 {
+		if err := s.prRepo.AddRequiredSkills(ctx, pr.PullRequestID, skills); err != nil {
+			return nil, nil, err
+		}
 	}
 
-	return pr, reviewers, nil
-}
+	assignment := &Assignment{Reviewers: []models.Candidate{}, Candidates: []models.Candidate{}}
+	if !in.Draft {
+		assignment, err = s.assignReviewers(ctx, pr, in.Strategy)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
 
-func (s *prService) GetPR(ctx context.Context, id string) (*models.PullRequest, []models.User, error) {
-	pr, err := s.prRepo.GetByID(ctx, id)
+	pr, _, err = s.withReviewers(ctx, pr)
 	if err != nil {
 		return nil, nil, err
 	}
-	revs, err := s.prRepo.ListReviewers(ctx, id)
-	return pr, revs, err
+	return pr, assignment, nil
 }
 
-func (s *prService) ListByReviewer(ctx context.Context, reviewerID string) ([]models.PullRequest, error) {
-	return s.prRepo.ListByReviewer(ctx, reviewerID)
+// authorTeam resolves the team a new PR belongs to: the requested one, which
+// the author must be a member of, or the author's only team.
+func authorTeam(author *models.User, requested string) (string, error) {
+	if requested != "" {
+		if !slices.Contains(author.Teams, requested) {
+			return "", ErrAuthorNotInTeam
+		}
+		return requested, nil
+	}
+	switch len(author.Teams) {
+	case 0:
+		return "", ErrAuthorHasNoTeam
+	case 1:
+		return author.Teams[0], nil
+	}
+	return "", ErrAmbiguousTeam
 }
 
-func (s *prService) MergePR(ctx context.Context, prID string) (*models.PullRequest, error) {
-	pr, err := s.prRepo.GetByID(ctx, prID)
+// assignReviewers fills the free reviewer slots of an open pull request:
+// first the seniors the team rules require, then one reviewer for each
+// required skill, then code owners of the changed paths, then anyone from
+// the team. Slots left empty because teammates are at capacity are queued.
+func (s *prService) assignReviewers(ctx context.Context, pr *models.PullRequest, strategy string) (*Assignment, error) {
+	current, err := s.prRepo.ListReviewers(ctx, pr.PullRequestID)
+	if err != nil {
+		return nil, err
+	}
+	required, err := s.prRepo.ListRequiredSkills(ctx, pr.PullRequestID)
+	if err != nil {
+		return nil, err
+	}
+	want := max(pr.ReviewerCount-len(current), 0)
+
+	chain, err := s.loadChain(ctx, pr.TeamName, strategy)
+	if err != nil {
+		return nil, err
+	}
+	assignment, err := s.selectSenior(ctx, chain, pr, current, want)
+	if err != nil {
+		return nil, err
+	}
+	picked := append([]models.User(nil), current...)
+	for _, r := range assignment.Reviewers {
+		picked = append(picked, r.User)
+	}
+	skilled, err := s.selectSkilled(ctx, chain, pr, required, picked, want-len(assignment.Reviewers))
 	if err != nil {
 		return nil, err
 	}
-	if pr.Status == "MERGED" {
-		return pr, nil
+	assignment.merge(skilled)
+	exclude := userIDs(current)
+	for _, r := range assignment.Reviewers {
+		exclude = append(exclude, r.UserID)
 	}
-	err = s.prRepo.SetMerged(ctx, prID)
+	owners, err := s.selectOwners(ctx, chain, pr, exclude, want-len(assignment.Reviewers))
 	if err != nil {
 		return nil, err
 	}
-	return s.prRepo.GetByID(ctx, prID)
+	assignment.merge(owners)
+	for _, r := range owners.Reviewers {
+		exclude = append(exclude, r.UserID)
+	}
+	fromTeam, err := chain.pick(ctx, pr.AuthorID, exclude, want-len(assignment.Reviewers))
+	if err != nil {
+		return nil, err
+	}
+	assignment.merge(fromTeam)
+
+	reviewers := append([]models.User(nil), current...)
+	for _, r := range assignment.Reviewers {
+		reviewers = append(reviewers, r.User)
+	}
+	assignment.Uncovered = uncoveredSkills(required, reviewers)
+	assignment.UnmetSeniority, err = s.unmetSeniority(ctx, pr, reviewers)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range assignment.Reviewers {
+		if err := s.prRepo.AddReviewer(ctx, pr.PullRequestID, r.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	// teammates exist but are full: wait for capacity instead of overloading them
+	if missing := want - len(assignment.Reviewers); missing > 0 && len(assignment.AtCapacity) > 0 {
+		if err := s.prRepo.EnqueueReviewers(ctx, pr.PullRequestID, missing); err != nil {
+			return nil, err
+		}
+		assignment.Queued = missing
+	}
+	return assignment, nil
 }
 
-func (s *prService) ReassignReviewer(ctx context.Context, prID string, oldReviewerID string) (*models.User, error) {
-	pr, err := s.prRepo.GetByID(ctx, prID)
+func (s *prService) GetPR(ctx context.Context, id string) (*models.PullRequestDetails, error) {
+	pr, err := s.prRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	if pr.Status == "MERGED" {
-		return nil, ErrCannotModifyMerged
+	pr, revs, err := s.withReviewers(ctx, pr)
+	if err != nil {
+		return nil, err
+	}
+	reviews, err := s.reviewRepo.ListByPR(ctx, id)
+	if err != nil {
+		return nil, err
 	}
+	history, err := s.eventRepo.ListByPR(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	skills, err := s.prRepo.ListRequiredSkills(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &models.PullRequestDetails{PR: pr, Reviewers: revs, Reviews: reviews, History: history, RequiredSkills: skills}, nil
+}
 
-	reviewers, err := s.prRepo.ListReviewers(ctx, prID)
+func (s *prService) ListByReviewer(ctx context.Context, reviewerID string) ([]models.ReviewAssignment, error) {
+	prs, err := s.prRepo.ListByReviewer(ctx, reviewerID)
+	if err != nil {
+		return nil, err
+	}
+	latest, err := s.reviewRepo.LatestByReviewer(ctx, reviewerID)
 	if err != nil {
 		return nil, err
 	}
 
-	// check old reviewer exists
-	found := false
-	for _, r := range reviewers {
-		if r.UserID == oldReviewerID {
-			found = true
+	list := make([]models.ReviewAssignment, 0, len(prs))
+	for _, pr := range prs {
+		item := models.ReviewAssignment{PullRequestShort: pr.Short()}
+		if rv, ok := latest[pr.PullRequestID]; ok {
+			item.Verdict = &rv.Verdict
+			item.ReviewedAt = &rv.CreatedAt
 		}
+		list = append(list, item)
 	}
-	if !found {
-		return nil, ErrReviewerNotInPR
+	return list, nil
+}
+
+func (s *prService) SubmitReview(ctx context.Context, prID string, reviewerID string, verdict models.ReviewVerdict, message *string) (*models.Review, error) {
+	if !verdict.Valid() {
+		return nil, ErrInvalidVerdict
 	}
 
-	// find new reviewer
-	teamUsers, err := s.userRepo.ListUsersByTeam(ctx, pr.TeamName)
+	review := &models.Review{
+		PullRequestID: prID,
+		ReviewerID:    reviewerID,
+		Verdict:       verdict,
+		Message:       message,
+	}
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		pr, err := s.prRepo.GetByIDForUpdate(ctx, prID)
+		if err != nil {
+			return err
+		}
+		if err := checkOpen(pr); err != nil {
+			return err
+		}
+		if verdict == models.VerdictApproved && reviewerID == pr.AuthorID {
+			team, err := effectiveTeam(ctx, s.teamRepo, pr.TeamName)
+			if err != nil {
+				return err
+			}
+			if mergePolicy(team).ForbidSelfApproval {
+				return ErrSelfApproval
+			}
+		}
+		reviewers, err := s.prRepo.ListReviewers(ctx, prID)
+		if err != nil {
+			return err
+		}
+		if !containsUser(reviewers, reviewerID) {
+			return ErrReviewerNotInPR
+		}
+		return s.reviewRepo.Create(ctx, review)
+	})
 	if err != nil {
 		return nil, err
 	}
+	return review, nil
+}
 
-	var candidates []models.User
-	for _, u := range teamUsers {
-		if u.UserID == oldReviewerID {
-			continue
+func userIDs(users []models.User) []string {
+	ids := make([]string, 0, len(users))
+	for _, u := range users {
+		ids = append(ids, u.UserID)
+	}
+	return ids
+}
+
+func containsUser(users []models.User, id string) bool {
+	for _, u := range users {
+		if u.UserID == id {
+			return true
 		}
-		if !u.IsActive {
-			continue
+	}
+	return false
+}
+
+func (s *prService) MergePR(ctx context.Context, in MergeInput) (*models.PullRequest, error) {
+	prID := in.PullRequestID
+	var merged bool
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		merged = false
+		pr, err := s.prRepo.GetByIDForUpdate(ctx, prID)
+		if err != nil {
+			return err
+		}
+		if pr.Status == models.PRStatusMerged {
+			return nil
 		}
-		alreadyAssigned := false
-		for _, r := range reviewers {
-			if r.UserID == u.UserID {
-				alreadyAssigned = true
-				break
+		if err := checkTransition(pr.Status, models.PRStatusMerged); err != nil {
+			return err
+		}
+
+		violation := s.checkMergePolicy(ctx, pr)
+		if in.Force {
+			if err := s.authorizeForce(ctx, in); err != nil {
+				return err
+			}
+			if violation != nil && apperr.CodeOf(violation) != apperr.CodeMergeBlocked {
+				return violation
 			}
+		} else if violation != nil {
+			return violation
 		}
-		if !alreadyAssigned {
-			candidates = append(candidates, u)
+
+		if err := s.prRepo.SetMerged(ctx, prID); err != nil {
+			return err
+		}
+		event, err := mergeEvent(in, violation)
+		if err != nil {
+			return err
+		}
+		merged = true
+		return s.eventRepo.Create(ctx, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if merged {
+		// merging frees review capacity
+		s.DrainReviewQueue(ctx)
+	}
+
+	pr, err := s.prRepo.GetByID(ctx, prID)
+	if err != nil {
+		return nil, err
+	}
+	pr, _, err = s.withReviewers(ctx, pr)
+	return pr, err
+}
+
+func (s *prService) ReassignReviewer(ctx context.Context, prID string, oldReviewerID string, strategy string) (*models.PullRequest, *Assignment, error) {
+	var pr *models.PullRequest
+	var assignment *Assignment
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		pr, assignment, err = s.reassignReviewer(ctx, prID, oldReviewerID, strategy)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	// the old reviewer has a free slot now
+	s.DrainReviewQueue(ctx)
+	return pr, assignment, nil
+}
+
+func (s *prService) reassignReviewer(ctx context.Context, prID string, oldReviewerID string, strategy string) (*models.PullRequest, *Assignment, error) {
+	// serializes reassignments and merges of the same PR
+	pr, err := s.prRepo.GetByIDForUpdate(ctx, prID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := s.userRepo.GetByID(ctx, oldReviewerID); err != nil {
+		return nil, nil, err
+	}
+	if err := checkOpen(pr); err != nil {
+		return nil, nil, err
+	}
+
+	reviewers, err := s.prRepo.ListReviewers(ctx, prID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// check old reviewer exists
+	if !containsUser(reviewers, oldReviewerID) {
+		return nil, nil, ErrReviewerNotInPR
+	}
+
+	// find new reviewer; when the old one is needed for the team seniority
+	// rules, the new one has to take their place in them
+	sen, err := s.prSeniority(ctx, pr, reviewers)
+	if err != nil {
+		return nil, nil, err
+	}
+	rule := sen.replacementRule(pr.AuthorID, reviewers, oldReviewerID)
+	chain, err := s.loadChain(ctx, pr.TeamName, strategy)
+	if err != nil {
+		return nil, nil, err
+	}
+	var assignment *Assignment
+	if rule != nil {
+		assignment, err = chain.pickWhere(ctx, pr.AuthorID, userIDs(reviewers), rule)
+		if err == nil && len(assignment.Reviewers) == 0 {
+			err = ErrSeniorityUnmet
 		}
+	} else {
+		assignment, err = chain.pick(ctx, pr.AuthorID, userIDs(reviewers), 1)
+	}
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if len(candidates) == 0 {
-		return nil, ErrNoAvailableReviewers
+	if len(assignment.Reviewers) == 0 {
+		if len(assignment.AtCapacity) > 0 {
+			return nil, nil, ErrAllAtCapacity
+		}
+		return nil, nil, ErrNoAvailableReviewers
 	}
 
-	newReviewer := candidates[0]
+	newReviewer := assignment.Reviewers[0]
 
-	_ = s.prRepo.RemoveReviewer(ctx, prID, oldReviewerID)
-	_ = s.prRepo.AddReviewer(ctx, prID, newReviewer.UserID)
+	if err := s.prRepo.RemoveReviewer(ctx, prID, oldReviewerID); err != nil {
+		return nil, nil, err
+	}
+	if err := s.prRepo.AddReviewer(ctx, prID, newReviewer.UserID); err != nil {
+		return nil, nil, err
+	}
+	event, err := newEvent(prID, models.EventReviewerReassigned, "", models.ReviewerChange{
+		PullRequestID: prID,
+		OldReviewerID: oldReviewerID,
+		NewReviewerID: newReviewer.UserID,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.eventRepo.Create(ctx, event); err != nil {
+		return nil, nil, err
+	}
 
-	return &newReviewer, nil
+	pr, _, err = s.withReviewers(ctx, pr)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pr, assignment, nil
 }
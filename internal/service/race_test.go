@@ -0,0 +1,161 @@
+package service_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"pr-reviewer/internal/apperr"
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/service"
+)
+
+// TestConcurrentReassignAndMerge fires reassignments at one PR while it is
+// being merged. Run it with -race.
+func TestConcurrentReassignAndMerge(t *testing.T) {
+	const (
+		prID      = "pr-race"
+		workers   = 8
+		attempts  = 10
+		maxReview = 2
+	)
+	ts := newTestServices(t)
+	prService, teamService, prRepo := ts.pr, ts.team, ts.prs
+	ctx := context.Background()
+
+	var members []models.TeamMember
+	for i := 1; i <= 6; i++ {
+		id := fmt.Sprintf("r%d", i)
+		members = append(members, models.TeamMember{UserID: id, Username: id, IsActive: true})
+	}
+	if _, err := teamService.AddTeam(ctx, "race", members); err != nil {
+		t.Fatalf("AddTeam: %v", err)
+	}
+	limit := maxReview
+	if _, err := teamService.UpdateSettings(ctx, "race", models.TeamSettings{MaxReviewers: &limit}); err != nil {
+		t.Fatalf("UpdateSettings: %v", err)
+	}
+	if _, _, err := prService.CreatePR(ctx, service.CreatePRInput{PullRequestID: prID, Name: "Race", AuthorID: "r1"}); err != nil {
+		t.Fatalf("CreatePR: %v", err)
+	}
+
+	// checkReviewers reports duplicates and more reviewers than the team allows.
+	checkReviewers := func(where string, ids []string) {
+		seen := make(map[string]bool, len(ids))
+		for _, id := range ids {
+			if seen[id] {
+				t.Errorf("%s: %s is assigned twice: %v", where, id, ids)
+			}
+			seen[id] = true
+		}
+		if len(ids) > maxReview {
+			t.Errorf("%s: %d reviewers, team maximum is %d: %v", where, len(ids), maxReview, ids)
+		}
+	}
+	currentReviewers := func() []string {
+		users, err := prRepo.ListReviewers(ctx, prID)
+		if err != nil {
+			t.Errorf("ListReviewers: %v", err)
+			return nil
+		}
+		ids := make([]string, 0, len(users))
+		for _, u := range users {
+			ids = append(ids, u.UserID)
+		}
+		slices.Sort(ids)
+		return ids
+	}
+
+	var (
+		mu          sync.Mutex
+		merged      bool
+		atMerge     []string
+		wg          sync.WaitGroup
+		halfway     = make(chan struct{})
+		halfwayOnce sync.Once
+		reassigned  atomic.Int32
+	)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-halfway
+		pr, err := prService.MergePR(ctx, service.MergeInput{PullRequestID: prID})
+		if err != nil {
+			t.Errorf("MergePR: %v", err)
+			return
+		}
+		if pr.Status != models.PRStatusMerged {
+			t.Errorf("status after merge = %s", pr.Status)
+		}
+		mu.Lock()
+		merged = true
+		atMerge = currentReviewers()
+		mu.Unlock()
+	}()
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < attempts; i++ {
+				if i == attempts/2 {
+					halfwayOnce.Do(func() { close(halfway) })
+				}
+				ids := currentReviewers()
+				if len(ids) == 0 {
+					continue
+				}
+				mu.Lock()
+				afterMerge := merged
+				mu.Unlock()
+
+				pr, _, err := prService.ReassignReviewer(ctx, prID, ids[(w+i)%len(ids)], "")
+				switch {
+				case err == nil:
+					reassigned.Add(1)
+					if afterMerge || pr.Status != models.PRStatusOpen {
+						t.Errorf("reassignment succeeded after the merge: %+v", pr)
+					}
+					checkReviewers("ReassignReviewer", pr.AssignedReviewers)
+				case afterMerge && apperr.CodeOf(err) != apperr.CodePRMerged:
+					t.Errorf("reassign after the merge: %v, want PR_MERGED", err)
+				default:
+					switch apperr.CodeOf(err) {
+					case apperr.CodeNotAssigned, apperr.CodeNoCandidate, apperr.CodePRMerged:
+						// lost the race to another worker or to the merge
+					default:
+						t.Errorf("ReassignReviewer: %v", err)
+					}
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if !merged {
+		t.Fatal("the PR was not merged")
+	}
+	final := currentReviewers()
+	checkReviewers("final", final)
+	if !slices.Equal(final, atMerge) {
+		t.Errorf("reviewers changed after the merge: %v, then %v", atMerge, final)
+	}
+
+	events, err := ts.events.ListByPR(ctx, prID)
+	if err != nil {
+		t.Fatalf("ListByPR: %v", err)
+	}
+	var logged int32
+	for _, e := range events {
+		if e.Type == models.EventReviewerReassigned {
+			logged++
+		}
+	}
+	if logged != reassigned.Load() {
+		t.Errorf("%d REVIEWER_REASSIGNED events for %d reassignments", logged, reassigned.Load())
+	}
+}
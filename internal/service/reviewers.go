@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"pr-reviewer/internal/apperr"
+	"pr-reviewer/internal/models"
+)
+
+var (
+	ErrInvalidReviewerBounds = apperr.New(apperr.CodeValidation, "reviewer counts must satisfy 0 <= min_reviewers <= default_reviewers <= max_reviewers")
+	ErrAlreadyReviewer       = apperr.New(apperr.CodeConflict, "user is already a reviewer of this PR")
+	ErrAuthorCannotReview    = apperr.New(apperr.CodeValidation, "author cannot review own pull request")
+	ErrReviewerInactive      = apperr.New(apperr.CodeValidation, "reviewer is not active")
+	ErrConflictOfInterest    = apperr.New(apperr.CodeConflictOfInterest, "reviewer is excluded from reviewing the author's pull requests")
+)
+
+// defaultReviewerBounds applies to teams that did not configure reviewer counts.
+var defaultReviewerBounds = models.ReviewerBounds{Default: 2, Min: 1, Max: 5}
+
+// reviewerBounds returns the effective reviewer counts of a team.
+func reviewerBounds(team *models.Team) models.ReviewerBounds {
+	b := defaultReviewerBounds
+	if team == nil {
+		return b
+	}
+	if team.DefaultReviewers != nil {
+		b.Default = *team.DefaultReviewers
+	}
+	if team.MinReviewers != nil {
+		b.Min = *team.MinReviewers
+	}
+	if team.MaxReviewers != nil {
+		b.Max = *team.MaxReviewers
+	}
+	return b
+}
+
+func validReviewerBounds(b models.ReviewerBounds) bool {
+	return 0 <= b.Min && b.Min <= b.Default && b.Default <= b.Max
+}
+
+func reviewerCountError(count int, b models.ReviewerBounds) error {
+	return apperr.New(apperr.CodeValidation, fmt.Sprintf("reviewer count %d is outside the team range %d..%d", count, b.Min, b.Max))
+}
+
+// AddReviewerInput adds a reviewer on top of the PR's current ones. An empty
+// UserID picks one with the team strategy, or Strategy when set.
+type AddReviewerInput struct {
+	PullRequestID string
+	UserID        string
+	ActorID       string
+	Strategy      string
+}
+
+func (s *prService) AddReviewer(ctx context.Context, in AddReviewerInput) (*models.PullRequest, *Assignment, error) {
+	var pr *models.PullRequest
+	var assignment *Assignment
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		pr, err = s.prRepo.GetByIDForUpdate(ctx, in.PullRequestID)
+		if err != nil {
+			return err
+		}
+		if err := checkOpen(pr); err != nil {
+			return err
+		}
+		team, err := effectiveTeam(ctx, s.teamRepo, pr.TeamName)
+		if err != nil {
+			return err
+		}
+		reviewers, err := s.prRepo.ListReviewers(ctx, pr.PullRequestID)
+		if err != nil {
+			return err
+		}
+		bounds := reviewerBounds(team)
+		count := max(pr.ReviewerCount, len(reviewers)) + 1
+		if count > bounds.Max {
+			return reviewerCountError(count, bounds)
+		}
+
+		reviewerID := in.UserID
+		if reviewerID == "" {
+			assignment, err = s.selectReviewers(ctx, pr.TeamName, pr.AuthorID, userIDs(reviewers), 1, in.Strategy)
+			if err != nil {
+				return err
+			}
+			if len(assignment.Reviewers) == 0 {
+				if len(assignment.AtCapacity) > 0 {
+					return ErrAllAtCapacity
+				}
+				return ErrNoAvailableReviewers
+			}
+			reviewerID = assignment.Reviewers[0].UserID
+		} else if err := s.checkExplicitReviewer(ctx, pr, reviewers, reviewerID); err != nil {
+			return err
+		}
+
+		if err := s.prRepo.AddReviewer(ctx, pr.PullRequestID, reviewerID); err != nil {
+			return err
+		}
+		if err := s.prRepo.SetReviewerCount(ctx, pr.PullRequestID, count); err != nil {
+			return err
+		}
+		details := struct {
+			ReviewerID string `json:"reviewer_id"`
+		}{reviewerID}
+		event, err := newEvent(pr.PullRequestID, models.EventReviewerAdded, in.ActorID, details)
+		if err != nil {
+			return err
+		}
+		if err := s.eventRepo.Create(ctx, event); err != nil {
+			return err
+		}
+
+		pr, err = s.reloadPR(ctx, pr.PullRequestID)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return pr, assignment, nil
+}
+
+func (s *prService) checkExplicitReviewer(ctx context.Context, pr *models.PullRequest, reviewers []models.User, userID string) error {
+	if userID == pr.AuthorID {
+		return ErrAuthorCannotReview
+	}
+	if containsUser(reviewers, userID) {
+		return ErrAlreadyReviewer
+	}
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.IsActive {
+		return ErrReviewerInactive
+	}
+	excluded, err := s.exclusionRepo.ExcludedReviewers(ctx, pr.AuthorID)
+	if err != nil {
+		return err
+	}
+	if excluded[userID] {
+		return ErrConflictOfInterest
+	}
+	return nil
+}
+
+// RemoveReviewer drops a reviewer without replacement, lowering the PR's
+// reviewer count; it cannot go below the team minimum nor drop a reviewer
+// the team seniority rules rely on.
+func (s *prService) RemoveReviewer(ctx context.Context, prID string, reviewerID string, actorID string) (*models.PullRequest, error) {
+	var pr *models.PullRequest
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		pr, err = s.prRepo.GetByIDForUpdate(ctx, prID)
+		if err != nil {
+			return err
+		}
+		if err := checkOpen(pr); err != nil {
+			return err
+		}
+		team, err := effectiveTeam(ctx, s.teamRepo, pr.TeamName)
+		if err != nil {
+			return err
+		}
+		reviewers, err := s.prRepo.ListReviewers(ctx, prID)
+		if err != nil {
+			return err
+		}
+		if !containsUser(reviewers, reviewerID) {
+			return ErrReviewerNotInPR
+		}
+		bounds := reviewerBounds(team)
+		count := len(reviewers) - 1
+		if count < bounds.Min {
+			return reviewerCountError(count, bounds)
+		}
+		if err := s.checkSeniorityKept(ctx, pr, reviewers, reviewerID); err != nil {
+			return err
+		}
+
+		if err := s.prRepo.RemoveReviewer(ctx, prID, reviewerID); err != nil {
+			return err
+		}
+		if err := s.prRepo.SetReviewerCount(ctx, prID, count); err != nil {
+			return err
+		}
+		details := struct {
+			ReviewerID string `json:"reviewer_id"`
+		}{reviewerID}
+		event, err := newEvent(prID, models.EventReviewerRemoved, actorID, details)
+		if err != nil {
+			return err
+		}
+		if err := s.eventRepo.Create(ctx, event); err != nil {
+			return err
+		}
+
+		pr, err = s.reloadPR(ctx, prID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	// the removed reviewer has a free slot now
+	s.DrainReviewQueue(ctx)
+	return pr, nil
+}
+
+// reloadPR reads the pull request again with its reviewers.
+func (s *prService) reloadPR(ctx context.Context, id string) (*models.PullRequest, error) {
+	pr, err := s.prRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	pr, _, err = s.withReviewers(ctx, pr)
+	return pr, err
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"sync"
+
+	"pr-reviewer/internal/apperr"
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/repository"
+)
+
+const (
+	StrategyRoundRobin  = "round_robin"
+	StrategyLeastLoaded = "least_loaded"
+	StrategyRandom      = "random"
+)
+
+var ErrUnknownStrategy = apperr.New(apperr.CodeValidation, "unknown reviewer selection strategy")
+
+// Rotations a team keeps a round robin cursor for: picks among all members
+// and picks among the code owners, so that the latter do not skip members
+// in the former.
+const (
+	rotationTeam       = "team"
+	rotationCodeOwners = "codeowners"
+)
+
+// SelectionRequest describes a single reviewer pick. Candidates are already
+// filtered (active, not the author, not assigned to the PR) and carry their
+// current open review load. Rotation names the team's round robin cursor the
+// pick advances.
+type SelectionRequest struct {
+	TeamName   string
+	Rotation   string
+	AuthorID   string
+	Candidates []models.Candidate
+	Count      int
+}
+
+// ReviewerSelector decides which of the candidates become reviewers.
+type ReviewerSelector interface {
+	Name() string
+	Select(ctx context.Context, req SelectionRequest) ([]models.Candidate, error)
+}
+
+// SelectorRegistry keeps the available strategies by name.
+type SelectorRegistry struct {
+	mu        sync.RWMutex
+	selectors map[string]ReviewerSelector
+	def       string
+}
+
+func NewSelectorRegistry(def string, selectors ...ReviewerSelector) *SelectorRegistry {
+	reg := &SelectorRegistry{selectors: make(map[string]ReviewerSelector), def: def}
+	for _, s := range selectors {
+		reg.Register(s)
+	}
+	return reg
+}
+
+func (r *SelectorRegistry) Register(s ReviewerSelector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.selectors[s.Name()] = s
+}
+
+func (r *SelectorRegistry) Get(name string) (ReviewerSelector, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.selectors[name]
+	if !ok {
+		return nil, ErrUnknownStrategy
+	}
+	return s, nil
+}
+
+// Resolve picks the strategy by priority: request override, team setting, default.
+func (r *SelectorRegistry) Resolve(override string, team *models.Team) (ReviewerSelector, error) {
+	if override != "" {
+		return r.Get(override)
+	}
+	if team != nil && team.ReviewerStrategy != nil && *team.ReviewerStrategy != "" {
+		return r.Get(*team.ReviewerStrategy)
+	}
+	return r.Get(r.def)
+}
+
+func sortByID(users []models.Candidate) []models.Candidate {
+	out := make([]models.Candidate, len(users))
+	copy(out, users)
+	sort.Slice(out, func(i, j int) bool { return out[i].UserID < out[j].UserID })
+	return out
+}
+
+// sortByLoad orders candidates by open reviews, breaking ties by user_id.
+func sortByLoad(users []models.Candidate) []models.Candidate {
+	out := make([]models.Candidate, len(users))
+	copy(out, users)
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].OpenReviews != out[j].OpenReviews {
+			return out[i].OpenReviews < out[j].OpenReviews
+		}
+		return out[i].UserID < out[j].UserID
+	})
+	return out
+}
+
+func limit(users []models.Candidate, n int) []models.Candidate {
+	if n < len(users) {
+		return users[:n]
+	}
+	return users
+}
+
+// roundRobinSelector rotates through team members ordered by user_id,
+// continuing after the last reviewer it picked in the team's rotation. The
+// position is stored with the team and locked while the assignment
+// transaction runs, so concurrent assignments and replicas share one rotation.
+type roundRobinSelector struct {
+	teams repository.TeamRepository
+}
+
+func NewRoundRobinSelector(teams repository.TeamRepository) ReviewerSelector {
+	return &roundRobinSelector{teams: teams}
+}
+
+func (s *roundRobinSelector) Name() string { return StrategyRoundRobin }
+
+func (s *roundRobinSelector) Select(ctx context.Context, req SelectionRequest) ([]models.Candidate, error) {
+	if req.Count <= 0 || len(req.Candidates) == 0 {
+		return nil, nil
+	}
+	sorted := sortByID(req.Candidates)
+
+	last, err := s.teams.LockLastAssigned(ctx, req.TeamName, req.Rotation)
+	if err != nil {
+		return nil, err
+	}
+	start := 0
+	if last != "" {
+		start = sort.Search(len(sorted), func(i int) bool { return sorted[i].UserID > last })
+	}
+	picked := make([]models.Candidate, 0, req.Count)
+	for i := 0; i < len(sorted) && len(picked) < req.Count; i++ {
+		picked = append(picked, sorted[(start+i)%len(sorted)])
+	}
+	if err := s.teams.SetLastAssigned(ctx, req.TeamName, req.Rotation, picked[len(picked)-1].UserID); err != nil {
+		return nil, err
+	}
+	return picked, nil
+}
+
+// leastLoadedSelector prefers candidates with the fewest open reviews.
+type leastLoadedSelector struct{}
+
+func NewLeastLoadedSelector() ReviewerSelector {
+	return leastLoadedSelector{}
+}
+
+func (leastLoadedSelector) Name() string { return StrategyLeastLoaded }
+
+func (leastLoadedSelector) Select(_ context.Context, req SelectionRequest) ([]models.Candidate, error) {
+	if req.Count <= 0 || len(req.Candidates) == 0 {
+		return nil, nil
+	}
+	return limit(sortByLoad(req.Candidates), req.Count), nil
+}
+
+// randomSelector shuffles candidates with a seeded source so runs are reproducible.
+type randomSelector struct {
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+func NewRandomSelector(seed int64) ReviewerSelector {
+	return &randomSelector{rnd: rand.New(rand.NewSource(seed))}
+}
+
+func (s *randomSelector) Name() string { return StrategyRandom }
+
+func (s *randomSelector) Select(_ context.Context, req SelectionRequest) ([]models.Candidate, error) {
+	if req.Count <= 0 || len(req.Candidates) == 0 {
+		return nil, nil
+	}
+	sorted := sortByID(req.Candidates)
+
+	s.mu.Lock()
+	s.rnd.Shuffle(len(sorted), func(i, j int) { sorted[i], sorted[j] = sorted[j], sorted[i] })
+	s.mu.Unlock()
+
+	return limit(sorted, req.Count), nil
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/repository"
+)
+
+// rotationRepo keeps the round robin cursors in memory, by team and rotation.
+type rotationRepo struct {
+	repository.TeamRepository
+	last map[[2]string]string
+}
+
+func (r *rotationRepo) LockLastAssigned(_ context.Context, name, rotation string) (string, error) {
+	return r.last[[2]string{name, rotation}], nil
+}
+
+func (r *rotationRepo) SetLastAssigned(_ context.Context, name, rotation, userID string) error {
+	r.last[[2]string{name, rotation}] = userID
+	return nil
+}
+
+// withLoad returns a candidate reviewing open pull requests.
+func withLoad(id string, open int) models.Candidate {
+	return models.Candidate{User: models.User{UserID: id}, OpenReviews: open}
+}
+
+func TestRoundRobinSelector(t *testing.T) {
+	tests := []struct {
+		name       string
+		last       string
+		candidates []string
+		count      int
+		want       []string
+	}{
+		{name: "starts at the lowest id", candidates: []string{"c", "a", "b"}, count: 2, want: []string{"a", "b"}},
+		{name: "continues after the cursor", last: "b", candidates: []string{"a", "b", "c"}, count: 2, want: []string{"c", "a"}},
+		{name: "wraps past the end", last: "z", candidates: []string{"a", "b"}, count: 1, want: []string{"a"}},
+		{name: "cursor left the team", last: "bb", candidates: []string{"a", "b", "c"}, count: 1, want: []string{"c"}},
+		{name: "fewer candidates than asked", candidates: []string{"b", "a"}, count: 5, want: []string{"a", "b"}},
+		{name: "nothing asked", last: "a", candidates: []string{"a", "b"}, count: 0, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &rotationRepo{last: map[[2]string]string{}}
+			if tt.last != "" {
+				repo.last[[2]string{"core", rotationTeam}] = tt.last
+			}
+			got, err := NewRoundRobinSelector(repo).Select(context.Background(), SelectionRequest{
+				TeamName:   "core",
+				Rotation:   rotationTeam,
+				Candidates: candidates(tt.candidates...),
+				Count:      tt.count,
+			})
+			if err != nil {
+				t.Fatalf("Select: %v", err)
+			}
+			if ids := candidateIDs(got); !slices.Equal(ids, tt.want) {
+				t.Errorf("picked %v, want %v", ids, tt.want)
+			}
+			want := tt.last
+			if len(tt.want) > 0 {
+				want = tt.want[len(tt.want)-1]
+			}
+			if cur := repo.last[[2]string{"core", rotationTeam}]; cur != want {
+				t.Errorf("cursor = %q, want %q", cur, want)
+			}
+		})
+	}
+}
+
+func TestRoundRobinRotationsAreSeparate(t *testing.T) {
+	repo := &rotationRepo{last: map[[2]string]string{{"core", rotationTeam}: "b"}}
+	sel := NewRoundRobinSelector(repo)
+	got, err := sel.Select(context.Background(), SelectionRequest{
+		TeamName:   "core",
+		Rotation:   rotationCodeOwners,
+		Candidates: candidates("a", "b", "c"),
+		Count:      1,
+	})
+	if err != nil {
+		t.Fatalf("Select: %v", err)
+	}
+	if ids := candidateIDs(got); !slices.Equal(ids, []string{"a"}) {
+		t.Errorf("code owners picked %v, want [a]", ids)
+	}
+	if cur := repo.last[[2]string{"core", rotationTeam}]; cur != "b" {
+		t.Errorf("team cursor = %q, want it untouched", cur)
+	}
+}
+
+func TestLeastLoadedSelector(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates []models.Candidate
+		count      int
+		want       []string
+	}{
+		{name: "fewest reviews first", candidates: []models.Candidate{withLoad("a", 2), withLoad("b", 0), withLoad("c", 1)}, count: 2, want: []string{"b", "c"}},
+		{name: "ties by id", candidates: []models.Candidate{withLoad("d", 1), withLoad("b", 1), withLoad("a", 3), withLoad("c", 1)}, count: 3, want: []string{"b", "c", "d"}},
+		{name: "fewer candidates than asked", candidates: []models.Candidate{withLoad("a", 0)}, count: 2, want: []string{"a"}},
+		{name: "no candidates", count: 2, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewLeastLoadedSelector().Select(context.Background(), SelectionRequest{Candidates: tt.candidates, Count: tt.count})
+			if err != nil {
+				t.Fatalf("Select: %v", err)
+			}
+			if ids := candidateIDs(got); !slices.Equal(ids, tt.want) {
+				t.Errorf("picked %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestRandomSelector(t *testing.T) {
+	req := SelectionRequest{Candidates: candidates("e", "d", "c", "b", "a"), Count: 3}
+	first, second := NewRandomSelector(42), NewRandomSelector(42)
+	for i := range 5 {
+		got, err := first.Select(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Select: %v", err)
+		}
+		ids := candidateIDs(got)
+		if len(ids) != req.Count {
+			t.Fatalf("picked %v, want %d reviewers", ids, req.Count)
+		}
+		for j, id := range ids {
+			if slices.Contains(ids[:j], id) || !slices.Contains(candidateIDs(req.Candidates), id) {
+				t.Errorf("picked %v: %s is repeated or not a candidate", ids, id)
+			}
+		}
+
+		// the candidate order does not matter for a given seed
+		reversed := slices.Clone(req.Candidates)
+		slices.Reverse(reversed)
+		again, err := second.Select(context.Background(), SelectionRequest{Candidates: reversed, Count: req.Count})
+		if err != nil {
+			t.Fatalf("Select: %v", err)
+		}
+		if !slices.Equal(candidateIDs(again), ids) {
+			t.Errorf("pick %d with the same seed = %v, want %v", i, candidateIDs(again), ids)
+		}
+	}
+}
+
+func TestSelectorRegistryResolve(t *testing.T) {
+	reg := NewSelectorRegistry(StrategyLeastLoaded, NewLeastLoadedSelector(), NewRandomSelector(1))
+	random, empty, unknown := StrategyRandom, "", "fastest"
+	tests := []struct {
+		name     string
+		override string
+		team     *models.Team
+		want     string
+		err      error
+	}{
+		{name: "default without a team", want: StrategyLeastLoaded},
+		{name: "team setting", team: &models.Team{TeamSettings: models.TeamSettings{ReviewerStrategy: &random}}, want: StrategyRandom},
+		{name: "empty team setting", team: &models.Team{TeamSettings: models.TeamSettings{ReviewerStrategy: &empty}}, want: StrategyLeastLoaded},
+		{name: "override wins", override: StrategyLeastLoaded, team: &models.Team{TeamSettings: models.TeamSettings{ReviewerStrategy: &random}}, want: StrategyLeastLoaded},
+		{name: "unknown override", override: unknown, err: ErrUnknownStrategy},
+		{name: "unknown team setting", team: &models.Team{TeamSettings: models.TeamSettings{ReviewerStrategy: &unknown}}, err: ErrUnknownStrategy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sel, err := reg.Resolve(tt.override, tt.team)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Resolve: %v, want %v", err, tt.err)
+			}
+			if err == nil && sel.Name() != tt.want {
+				t.Errorf("Resolve = %s, want %s", sel.Name(), tt.want)
+			}
+		})
+	}
+}
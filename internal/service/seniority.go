@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+
+	"pr-reviewer/internal/apperr"
+	"pr-reviewer/internal/models"
+)
+
+var (
+	ErrInvalidLevel      = apperr.New(apperr.CodeValidation, "level must be one of junior, middle, senior, lead")
+	ErrInvalidMinSeniors = apperr.New(apperr.CodeValidation, "min_senior_reviewers must be between 0 and max_reviewers")
+	ErrSeniorityUnmet    = apperr.New(apperr.CodeNoCandidate, "no reviewer available to satisfy the team seniority rules")
+	ErrSeniorityRequired = apperr.New(apperr.CodeConflict, "reviewer is needed to satisfy the team seniority rules")
+)
+
+var levelRank = map[string]int{
+	models.LevelJunior: 1,
+	models.LevelMiddle: 2,
+	models.LevelSenior: 3,
+	models.LevelLead:   4,
+}
+
+// checkLevel rejects unknown levels; a nil level keeps the stored one.
+func checkLevel(level *string) error {
+	if level == nil {
+		return nil
+	}
+	if _, ok := levelRank[*level]; !ok {
+		return ErrInvalidLevel
+	}
+	return nil
+}
+
+func seniorOrAbove(level string) bool {
+	return levelRank[level] >= levelRank[models.LevelSenior]
+}
+
+// seniorityRules is what the team rules require from the reviewers of one
+// pull request: a number of reviewers at senior level or above and, for
+// PRs of juniors, a lead. A lead counts as a senior as well.
+type seniorityRules struct {
+	seniors int
+	lead    bool
+}
+
+func (r seniorityRules) met() bool {
+	return r.seniors == 0 && !r.lead
+}
+
+// unmet returns what is still required given the levels of the reviewers.
+func (r seniorityRules) unmet(levels []string) seniorityRules {
+	for _, l := range levels {
+		if seniorOrAbove(l) {
+			r.seniors = max(r.seniors-1, 0)
+		}
+		if l == models.LevelLead {
+			r.lead = false
+		}
+	}
+	return r
+}
+
+// names lists the team settings behind the rules, for reporting unmet ones.
+func (r seniorityRules) names() []string {
+	var res []string
+	if r.seniors > 0 {
+		res = append(res, "min_senior_reviewers")
+	}
+	if r.lead {
+		res = append(res, "junior_needs_lead")
+	}
+	return res
+}
+
+// qualifies reports whether a reviewer of the level brings the rules closer to being met.
+func (r seniorityRules) qualifies(level string) bool {
+	if r.lead {
+		return level == models.LevelLead
+	}
+	return r.seniors > 0 && seniorOrAbove(level)
+}
+
+// teamSeniority is a team's effective seniority settings together with the
+// levels of the users they are checked against, so that the rules of many
+// pull requests of the team resolve without further queries.
+type teamSeniority struct {
+	minSeniors      int
+	juniorNeedsLead bool
+	levels          map[string]string
+}
+
+// loadSeniority resolves the settings of the team and, when it has rules,
+// the levels of the given authors and reviewers in it. Candidate pools of
+// other teams carry the same levels, see poolChain.pool.
+func (s *prService) loadSeniority(ctx context.Context, teamName string, userIDs []string) (*teamSeniority, error) {
+	team, err := effectiveTeam(ctx, s.teamRepo, teamName)
+	if err != nil {
+		return nil, err
+	}
+	return s.teamSeniority(ctx, team, teamName, userIDs)
+}
+
+// teamSeniority is loadSeniority for an already resolved team.
+func (s *prService) teamSeniority(ctx context.Context, team *models.Team, teamName string, userIDs []string) (*teamSeniority, error) {
+	ts := &teamSeniority{juniorNeedsLead: team.JuniorNeedsLead != nil && *team.JuniorNeedsLead}
+	if team.MinSeniorReviewers != nil {
+		ts.minSeniors = *team.MinSeniorReviewers
+	}
+	if ts.none() || len(userIDs) == 0 {
+		return ts, nil
+	}
+	var err error
+	ts.levels, err = s.userRepo.LevelsOf(ctx, teamName, userIDs)
+	return ts, err
+}
+
+// prSeniority loads the seniority of the pull request's team for its author
+// and the given reviewers.
+func (s *prService) prSeniority(ctx context.Context, pr *models.PullRequest, reviewers []models.User) (*teamSeniority, error) {
+	return s.loadSeniority(ctx, pr.TeamName, append([]string{pr.AuthorID}, userIDs(reviewers)...))
+}
+
+// none reports whether the team has no seniority rules at all.
+func (t *teamSeniority) none() bool {
+	return t.minSeniors == 0 && !t.juniorNeedsLead
+}
+
+// rules returns what the team requires from the reviewers of a pull request
+// by the author.
+func (t *teamSeniority) rules(authorID string) seniorityRules {
+	return seniorityRules{
+		seniors: t.minSeniors,
+		lead:    t.juniorNeedsLead && t.levels[authorID] == models.LevelJunior,
+	}
+}
+
+// remember records the level of a reviewer picked from a candidate pool.
+func (t *teamSeniority) remember(u models.User) {
+	if t.levels == nil {
+		t.levels = make(map[string]string)
+	}
+	t.levels[u.UserID] = u.Level
+}
+
+func (t *teamSeniority) levelsOf(users []models.User) []string {
+	levels := make([]string, 0, len(users))
+	for _, u := range users {
+		levels = append(levels, t.levels[u.UserID])
+	}
+	return levels
+}
+
+// replacementRule returns the filter a replacement for the leaving reviewer
+// has to pass so that the seniority rules stay met, or nil when any
+// candidate will do.
+func (t *teamSeniority) replacementRule(authorID string, reviewers []models.User, leavingID string) func(models.User) bool {
+	rules := t.rules(authorID)
+	if rules.met() {
+		return nil
+	}
+	var remaining []models.User
+	for _, u := range reviewers {
+		if u.UserID != leavingID {
+			remaining = append(remaining, u)
+		}
+	}
+	need := rules.unmet(t.levelsOf(remaining))
+	if need.met() {
+		return nil
+	}
+	return func(u models.User) bool { return need.qualifies(u.Level) }
+}
+
+// selectSenior picks reviewers until the seniority rules are met on top of
+// the current reviewers, as far as the free slots and the candidates allow.
+// Rules it cannot meet are reported by unmetSeniority.
+func (s *prService) selectSenior(ctx context.Context, chain *poolChain, pr *models.PullRequest, current []models.User, count int) (*Assignment, error) {
+	result := &Assignment{Reviewers: []models.Candidate{}, Candidates: []models.Candidate{}}
+	sen, err := s.prSeniority(ctx, pr, current)
+	if err != nil {
+		return nil, err
+	}
+	rules := sen.rules(pr.AuthorID)
+	if rules.met() {
+		return result, nil
+	}
+	levels := sen.levelsOf(current)
+
+	exclude := userIDs(current)
+	noLead := false
+	for len(result.Reviewers) < count {
+		need := rules.unmet(levels)
+		// without a lead to pick, seniors can still be
+		need.lead = need.lead && !noLead
+		if need.met() {
+			break
+		}
+		a, err := chain.pickWhere(ctx, pr.AuthorID, exclude, func(u models.User) bool {
+			return need.qualifies(u.Level)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(a.Reviewers) == 0 {
+			if need.lead {
+				noLead = true
+				continue
+			}
+			break
+		}
+		r := a.Reviewers[0]
+		result.Reviewers = append(result.Reviewers, r)
+		result.OffHours = append(result.OffHours, a.OffHours...)
+		levels = append(levels, r.Level)
+		exclude = append(exclude, r.UserID)
+	}
+	return result, nil
+}
+
+// unmetSeniority lists the seniority rules the reviewers do not satisfy.
+func (s *prService) unmetSeniority(ctx context.Context, pr *models.PullRequest, reviewers []models.User) ([]string, error) {
+	sen, err := s.prSeniority(ctx, pr, reviewers)
+	if err != nil {
+		return nil, err
+	}
+	return sen.rules(pr.AuthorID).unmet(sen.levelsOf(reviewers)).names(), nil
+}
+
+// checkSeniorityKept fails when the seniority rules need the leaving
+// reviewer, i.e. the reviewers without them satisfy less of the rules.
+func (s *prService) checkSeniorityKept(ctx context.Context, pr *models.PullRequest, reviewers []models.User, leavingID string) error {
+	sen, err := s.prSeniority(ctx, pr, reviewers)
+	if err != nil {
+		return err
+	}
+	rules := sen.rules(pr.AuthorID)
+	if rules.met() {
+		return nil
+	}
+	var remaining []models.User
+	for _, u := range reviewers {
+		if u.UserID != leavingID {
+			remaining = append(remaining, u)
+		}
+	}
+	if rules.unmet(sen.levelsOf(remaining)) != rules.unmet(sen.levelsOf(reviewers)) {
+		return ErrSeniorityRequired
+	}
+	return nil
+}
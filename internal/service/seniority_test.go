@@ -0,0 +1,163 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"pr-reviewer/internal/models"
+)
+
+const (
+	junior = models.LevelJunior
+	middle = models.LevelMiddle
+	senior = models.LevelSenior
+	lead   = models.LevelLead
+)
+
+func TestCheckLevel(t *testing.T) {
+	tests := []struct {
+		level *string
+		want  error
+	}{
+		{nil, nil},
+		{strPtr(junior), nil},
+		{strPtr(lead), nil},
+		{strPtr(""), ErrInvalidLevel},
+		{strPtr("Senior"), ErrInvalidLevel},
+		{strPtr("principal"), ErrInvalidLevel},
+	}
+	for _, tt := range tests {
+		if err := checkLevel(tt.level); !errors.Is(err, tt.want) {
+			t.Errorf("checkLevel(%v) = %v, want %v", tt.level, err, tt.want)
+		}
+	}
+}
+
+func TestSeniorityRulesUnmet(t *testing.T) {
+	tests := []struct {
+		name   string
+		rules  seniorityRules
+		levels []string
+		want   seniorityRules
+		names  []string
+	}{
+		{name: "no rules", levels: []string{junior}},
+		{name: "seniors missing", rules: seniorityRules{seniors: 2}, levels: []string{junior, senior}, want: seniorityRules{seniors: 1}, names: []string{"min_senior_reviewers"}},
+		{name: "seniors met", rules: seniorityRules{seniors: 2}, levels: []string{senior, lead, senior}},
+		{name: "lead counts as a senior", rules: seniorityRules{seniors: 1, lead: true}, levels: []string{lead}},
+		{name: "senior is no lead", rules: seniorityRules{seniors: 1, lead: true}, levels: []string{senior}, want: seniorityRules{lead: true}, names: []string{"junior_needs_lead"}},
+		{name: "nothing met", rules: seniorityRules{seniors: 1, lead: true}, levels: []string{middle, ""}, want: seniorityRules{seniors: 1, lead: true}, names: []string{"min_senior_reviewers", "junior_needs_lead"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.rules.unmet(tt.levels)
+			if got != tt.want {
+				t.Errorf("unmet = %+v, want %+v", got, tt.want)
+			}
+			if got.met() != (tt.want == seniorityRules{}) {
+				t.Errorf("met = %v for %+v", got.met(), got)
+			}
+			if !slices.Equal(got.names(), tt.names) {
+				t.Errorf("names = %v, want %v", got.names(), tt.names)
+			}
+		})
+	}
+}
+
+func TestSeniorityRulesQualifies(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules seniorityRules
+		want  []string
+	}{
+		{name: "nothing needed"},
+		{name: "seniors needed", rules: seniorityRules{seniors: 1}, want: []string{senior, lead}},
+		{name: "lead needed", rules: seniorityRules{seniors: 2, lead: true}, want: []string{lead}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, l := range []string{junior, middle, senior, lead} {
+				if tt.rules.qualifies(l) {
+					got = append(got, l)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("qualifying levels = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTeamSeniorityRules(t *testing.T) {
+	levels := map[string]string{"jun": junior, "mid": middle, "boss": lead}
+	tests := []struct {
+		name   string
+		team   teamSeniority
+		author string
+		want   seniorityRules
+	}{
+		{name: "no rules", team: teamSeniority{levels: levels}, author: "jun"},
+		{name: "seniors for everyone", team: teamSeniority{minSeniors: 2, levels: levels}, author: "mid", want: seniorityRules{seniors: 2}},
+		{name: "lead for a junior", team: teamSeniority{juniorNeedsLead: true, levels: levels}, author: "jun", want: seniorityRules{lead: true}},
+		{name: "no lead for a middle", team: teamSeniority{juniorNeedsLead: true, levels: levels}, author: "mid"},
+		{name: "unknown author", team: teamSeniority{juniorNeedsLead: true, levels: levels}, author: "ghost"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.team.rules(tt.author); got != tt.want {
+				t.Errorf("rules = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplacementRule(t *testing.T) {
+	team := teamSeniority{
+		minSeniors:      1,
+		juniorNeedsLead: true,
+		levels:          map[string]string{"jun": junior, "mid": middle, "mid2": middle, "sen": senior, "boss": lead},
+	}
+	users := func(ids ...string) []models.User {
+		res := make([]models.User, 0, len(ids))
+		for _, id := range ids {
+			res = append(res, models.User{UserID: id})
+		}
+		return res
+	}
+	tests := []struct {
+		name      string
+		author    string
+		reviewers []models.User
+		leaving   string
+		// levels a replacement may have, nil when any candidate will do
+		want []string
+	}{
+		{name: "other reviewer keeps the rules", author: "mid", reviewers: users("sen", "boss"), leaving: "sen"},
+		{name: "leaving senior", author: "mid", reviewers: users("sen", "mid2"), leaving: "sen", want: []string{senior, lead}},
+		{name: "leaving middle", author: "mid", reviewers: users("sen", "mid2"), leaving: "mid2"},
+		{name: "leaving lead of a junior", author: "jun", reviewers: users("boss", "sen"), leaving: "boss", want: []string{lead}},
+		{name: "rules unmet anyway", author: "jun", reviewers: users("mid", "mid2"), leaving: "mid", want: []string{lead}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := team.replacementRule(tt.author, tt.reviewers, tt.leaving)
+			if rule == nil {
+				if tt.want != nil {
+					t.Fatalf("replacementRule = nil, want %v", tt.want)
+				}
+				return
+			}
+			var got []string
+			for _, l := range []string{junior, middle, senior, lead} {
+				if rule(models.User{Level: l}) {
+					got = append(got, l)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("replacement levels = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
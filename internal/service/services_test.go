@@ -0,0 +1,51 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"pr-reviewer/internal/repository"
+	"pr-reviewer/internal/service"
+	"pr-reviewer/internal/store/storetest"
+)
+
+// testServices wires the services to a throwaway database, like
+// cmd/server does.
+type testServices struct {
+	users  repository.UserRepository
+	teams  repository.TeamRepository
+	prs    repository.PRRepository
+	events repository.EventRepository
+	pr     service.PRService
+	team   service.TeamService
+	user   service.UserService
+}
+
+func newTestServices(t *testing.T) *testServices {
+	t.Helper()
+	s := storetest.New(t)
+	pool := s.GetPool()
+
+	ts := &testServices{
+		users:  repository.NewUserRepositoryPG(pool),
+		teams:  repository.NewTeamRepositoryPG(pool),
+		prs:    repository.NewPRRepositoryPG(pool),
+		events: repository.NewEventRepositoryPG(pool),
+	}
+	selectors := service.NewSelectorRegistry(
+		service.StrategyLeastLoaded,
+		service.NewRoundRobinSelector(ts.teams),
+		service.NewLeastLoadedSelector(),
+		service.NewRandomSelector(1),
+		service.NewLeastPairedSelector(ts.prs, 30*24*time.Hour),
+	)
+	absences := repository.NewAbsenceRepositoryPG(pool)
+	exclusions := repository.NewExclusionRepositoryPG(pool)
+	ts.pr = service.NewPRService(s, ts.prs, ts.users, ts.teams,
+		repository.NewReviewRepositoryPG(pool), ts.events, absences, exclusions,
+		selectors, zap.NewNop())
+	ts.team = service.NewTeamService(s, ts.teams, ts.users, ts.prs, selectors, ts.pr)
+	ts.user = service.NewUserService(s, ts.users, ts.teams, absences, exclusions, ts.pr, t.TempDir())
+	return ts
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"pr-reviewer/internal/apperr"
+	"pr-reviewer/internal/models"
+)
+
+var ErrInvalidSkill = apperr.New(apperr.CodeValidation, "skills must not be empty")
+
+// normalizeSkills lower-cases and trims the skills and drops duplicates.
+func normalizeSkills(skills []string) ([]string, error) {
+	out := make([]string, 0, len(skills))
+	for _, s := range skills {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s == "" {
+			return nil, ErrInvalidSkill
+		}
+		if !slices.Contains(out, s) {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+// uncoveredSkills returns the required skills none of the reviewers has.
+func uncoveredSkills(required []string, reviewers []models.User) []string {
+	var out []string
+	for _, skill := range required {
+		covered := slices.ContainsFunc(reviewers, func(u models.User) bool {
+			return slices.Contains(u.Skills, skill)
+		})
+		if !covered {
+			out = append(out, skill)
+		}
+	}
+	return out
+}
+
+// selectSkilled picks up to count reviewers so that each required skill not
+// yet covered by the current reviewers gets one reviewer having it. Skills
+// are handled in order; a reviewer covers all the skills they have.
+func (s *prService) selectSkilled(ctx context.Context, chain *poolChain, pr *models.PullRequest, required []string, current []models.User, count int) (*Assignment, error) {
+	result := &Assignment{Reviewers: []models.Candidate{}, Candidates: []models.Candidate{}}
+	reviewers := append([]models.User(nil), current...)
+	exclude := userIDs(current)
+	for _, skill := range uncoveredSkills(required, current) {
+		if len(result.Reviewers) >= count {
+			break
+		}
+		if len(uncoveredSkills([]string{skill}, reviewers)) == 0 {
+			continue
+		}
+		a, err := chain.pickWhere(ctx, pr.AuthorID, exclude, func(u models.User) bool {
+			return slices.Contains(u.Skills, skill)
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range a.Reviewers {
+			result.Reviewers = append(result.Reviewers, r)
+			reviewers = append(reviewers, r.User)
+			exclude = append(exclude, r.UserID)
+		}
+		result.OffHours = append(result.OffHours, a.OffHours...)
+	}
+	return result, nil
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"pr-reviewer/internal/models"
+)
+
+func TestNormalizeSkills(t *testing.T) {
+	tests := []struct {
+		name   string
+		skills []string
+		want   []string
+		err    error
+	}{
+		{name: "none", skills: nil, want: []string{}},
+		{name: "lower-cased and trimmed", skills: []string{" Go ", "SQL"}, want: []string{"go", "sql"}},
+		{name: "duplicates dropped in order", skills: []string{"sql", "Go", "SQL", "go "}, want: []string{"sql", "go"}},
+		{name: "blank", skills: []string{"go", "  "}, err: ErrInvalidSkill},
+		{name: "empty", skills: []string{""}, err: ErrInvalidSkill},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeSkills(tt.skills)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("normalizeSkills: %v, want %v", err, tt.err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("normalizeSkills = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUncoveredSkills(t *testing.T) {
+	skilled := func(skills ...string) models.User { return models.User{Skills: skills} }
+	tests := []struct {
+		name      string
+		required  []string
+		reviewers []models.User
+		want      []string
+	}{
+		{name: "nothing required", reviewers: []models.User{skilled("go")}},
+		{name: "no reviewers", required: []string{"go", "sql"}, want: []string{"go", "sql"}},
+		{name: "covered by one", required: []string{"go", "sql"}, reviewers: []models.User{skilled("sql", "go")}},
+		{name: "covered by several", required: []string{"go", "sql"}, reviewers: []models.User{skilled("go"), skilled("sql")}},
+		{name: "partly covered", required: []string{"go", "k8s", "sql"}, reviewers: []models.User{skilled("sql"), skilled()}, want: []string{"go", "k8s"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := uncoveredSkills(tt.required, tt.reviewers); !slices.Equal(got, tt.want) {
+				t.Errorf("uncoveredSkills = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
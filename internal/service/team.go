@@ -3,8 +3,12 @@ package service
 import (
 	"context"
 	"errors"
+	"io"
+	"pr-reviewer/internal/apperr"
 	"pr-reviewer/internal/models"
 	"pr-reviewer/internal/repository"
+	"pr-reviewer/internal/store"
+	"time"
 )
 
 type TeamService interface {
@@ -12,49 +16,235 @@ type TeamService interface {
 	GetTeam(ctx context.Context, name string) (*models.Team, error)
 	ListTeams(ctx context.Context) ([]models.Team, error)
 	DeleteTeam(ctx context.Context, name string) error
-	AttachUser(ctx context.Context, teamName string, userID *string, username string, isActive bool) error
+	// AddTeam creates a team and creates or updates its members.
+	AddTeam(ctx context.Context, teamName string, members []models.TeamMember) (*models.TeamDetails, error)
+	GetTeamDetails(ctx context.Context, name string) (*models.TeamDetails, error)
+	UpdateSettings(ctx context.Context, name string, settings models.TeamSettings) (*models.Team, error)
+	// AttachUser creates or updates the user and adds them to the team with
+	// the role and level of m. An empty role or level keeps the stored one.
+	AttachUser(ctx context.Context, teamName string, userID *string, m models.TeamMember) error
+	// SetMember adds an existing user to the team or changes their membership.
+	// Deactivating a membership hands off the user's reviews of the team's
+	// OPEN pull requests.
+	SetMember(ctx context.Context, in SetMemberInput) (*models.Membership, error)
+	// RemoveMember drops the user from the team, handing off their reviews of
+	// the team's OPEN pull requests like a deactivation does.
+	RemoveMember(ctx context.Context, teamName string, userID string) error
+	GetFallbacks(ctx context.Context, name string) ([]string, error)
+	// SetFallbacks replaces the fallback teams; their order is the priority.
+	SetFallbacks(ctx context.Context, name string, fallbacks []string) ([]string, error)
+	SetParent(ctx context.Context, name string, parent *string) (*models.Team, error)
+	GetTeamTree(ctx context.Context, name string) (*models.TeamNode, error)
+	GetCodeOwners(ctx context.Context, name string) (*CodeOwners, error)
+	// SetCodeOwners replaces the team's CODEOWNERS file.
+	SetCodeOwners(ctx context.Context, name string, r io.Reader) (*CodeOwners, error)
+	PairingMatrix(ctx context.Context, name string, since, until time.Time) (*models.PairingMatrix, error)
 }
 
-var ErrTeamHasMembers = errors.New("team has members")
+var (
+	ErrTeamHasMembers  = apperr.New(apperr.CodeConflict, "team has members, cannot delete")
+	ErrTeamExists      = apperr.New(apperr.CodeTeamExists, "team_name already exists")
+	ErrInvalidFallback = apperr.New(apperr.CodeValidation, "fallback teams must be distinct and differ from the team itself")
+	ErrInvalidRole     = apperr.New(apperr.CodeValidation, "role must be one of member, lead")
+)
 
 type teamService struct {
-	teams repository.TeamRepository
-	users repository.UserRepository
+	tx        store.Transactor
+	teams     repository.TeamRepository
+	users     repository.UserRepository
+	prs       repository.PRRepository
+	selectors *SelectorRegistry
+	prService PRService
 }
 
-func NewTeamService(t repository.TeamRepository, u repository.UserRepository) TeamService {
-	return &teamService{teams: t, users: u}
+func NewTeamService(
+	tx store.Transactor,
+	t repository.TeamRepository,
+	u repository.UserRepository,
+	prs repository.PRRepository,
+	selectors *SelectorRegistry,
+	prService PRService,
+) TeamService {
+	return &teamService{tx: tx, teams: t, users: u, prs: prs, selectors: selectors, prService: prService}
 }
 
 func (s *teamService) AttachUser(
 	ctx context.Context,
 	teamName string,
 	userID *string,
-	username string,
-	isActive bool,
+	m models.TeamMember,
 ) error {
+	if m.Role != "" {
+		if err := checkRole(&m.Role); err != nil {
+			return err
+		}
+	}
+	if m.Level != "" {
+		if err := checkLevel(&m.Level); err != nil {
+			return err
+		}
+	}
 
-	// Проверяем что команда существует
-	_, err := s.teams.GetByName(ctx, teamName)
-	if err != nil {
-		return errors.New("team not found")
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		// Проверяем что команда существует
+		if _, err := s.teams.GetByName(ctx, teamName); err != nil {
+			return err
+		}
+
+		var user *models.User
+		var err error
+		if userID == nil {
+			// CASE 1: user_id не передан → создаём нового юзера
+			display := m.Username
+			user, err = s.users.Create(ctx, m.Username, &display)
+		} else {
+			// CASE 2: user_id передан — создаём с этим id или обновляем существующего
+			user, err = s.users.Upsert(ctx, *userID, m.Username, m.IsActive)
+		}
+		if err != nil {
+			return err
+		}
+		return s.users.AddMembership(ctx, &models.Membership{
+			TeamName: teamName,
+			UserID:   user.UserID,
+			IsActive: true,
+			Role:     m.Role,
+			Level:    m.Level,
+		})
+	})
+}
+
+// checkRole rejects unknown roles; a nil role keeps the stored one.
+func checkRole(role *string) error {
+	if role == nil {
+		return nil
 	}
+	switch *role {
+	case models.RoleMember, models.RoleLead:
+		return nil
+	}
+	return ErrInvalidRole
+}
 
-	// CASE 1: user_id не передан → создаём нового юзера
-	if userID == nil {
-		display := username
-		_, err = s.users.Create(ctx, username, &display, &teamName)
-		return err
+// SetMemberInput adds a user to a team or changes their membership. A nil
+// Role or Level keeps the stored value; new members get member and middle.
+type SetMemberInput struct {
+	TeamName string
+	UserID   string
+	IsActive bool
+	Role     *string
+	Level    *string
+}
+
+func (s *teamService) SetMember(ctx context.Context, in SetMemberInput) (*models.Membership, error) {
+	if err := checkRole(in.Role); err != nil {
+		return nil, err
+	}
+	if err := checkLevel(in.Level); err != nil {
+		return nil, err
+	}
+	m := models.Membership{TeamName: in.TeamName, UserID: in.UserID, IsActive: in.IsActive}
+	if in.Role != nil {
+		m.Role = *in.Role
+	}
+	if in.Level != nil {
+		m.Level = *in.Level
 	}
 
-	// CASE 2: user_id передан — обновляем существующего
-	_, err = s.users.GetByID(ctx, *userID)
+	var wasActive bool
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		wasActive = false
+		if _, err := s.teams.GetByName(ctx, m.TeamName); err != nil {
+			return err
+		}
+		if _, err := s.users.GetByID(ctx, m.UserID); err != nil {
+			return err
+		}
+		memberships, err := s.users.ListMemberships(ctx, m.UserID)
+		if err != nil {
+			return err
+		}
+		for _, cur := range memberships {
+			if cur.TeamName == m.TeamName {
+				wasActive = cur.IsActive
+			}
+		}
+		if err := s.users.AddMembership(ctx, &m); err != nil {
+			return err
+		}
+		if wasActive && !m.IsActive {
+			_, err = s.prService.HandOffReviews(ctx, []string{m.UserID}, m.TeamName)
+			return err
+		}
+		return nil
+	})
 	if err != nil {
+		return nil, err
+	}
+	if !wasActive && m.IsActive {
+		// a new or reactivated member can take queued reviews
+		s.prService.DrainReviewQueue(ctx)
+	}
+	return &m, nil
+}
+
+func (s *teamService) RemoveMember(ctx context.Context, teamName string, userID string) error {
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if _, err := s.teams.GetByName(ctx, teamName); err != nil {
+			return err
+		}
+		if _, err := s.prService.HandOffReviews(ctx, []string{userID}, teamName); err != nil {
+			return err
+		}
+		return s.users.RemoveMembership(ctx, teamName, userID)
+	})
+}
+
+func (s *teamService) AddTeam(ctx context.Context, teamName string, members []models.TeamMember) (*models.TeamDetails, error) {
+	var details *models.TeamDetails
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if _, err := s.teams.GetByName(ctx, teamName); err == nil {
+			return ErrTeamExists
+		} else if !errors.Is(err, repository.ErrTeamNotFound) {
+			return err
+		}
+
+		if _, err := s.teams.Create(ctx, teamName, nil); err != nil {
+			if apperr.CodeOf(err) == apperr.CodeAlreadyExists {
+				return ErrTeamExists
+			}
+			return err
+		}
+		for _, m := range members {
+			var userID *string
+			if m.UserID != "" {
+				userID = &m.UserID
+			}
+			if err := s.AttachUser(ctx, teamName, userID, m); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		details, err = s.GetTeamDetails(ctx, teamName)
 		return err
+	})
+	if err != nil {
+		return nil, err
 	}
+	return details, nil
+}
 
-	display := username
-	return s.users.Update(ctx, *userID, &display, &isActive, &teamName)
+func (s *teamService) GetTeamDetails(ctx context.Context, name string) (*models.TeamDetails, error) {
+	team, err := s.teams.GetByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.users.ListMembers(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return &models.TeamDetails{Team: *team, Members: members}, nil
 }
 
 func (s *teamService) CreateTeam(ctx context.Context, teamName string, description *string) (*models.Team, error) {
@@ -72,10 +262,82 @@ func (s *teamService) ListTeams(ctx context.Context) ([]models.Team, error) {
 func (s *teamService) DeleteTeam(ctx context.Context, name string) error {
 	err := s.teams.Delete(ctx, name)
 	if err != nil {
-		if errors.Is(err, repository.ErrForeignKeyViolation) {
+		if apperr.CodeOf(err) == apperr.CodeInvalidReference {
 			return ErrTeamHasMembers
 		}
 		return err
 	}
 	return nil
 }
+
+func (s *teamService) UpdateSettings(ctx context.Context, name string, settings models.TeamSettings) (*models.Team, error) {
+	// an empty strategy resets the team to the inherited or default one
+	if settings.ReviewerStrategy != nil && *settings.ReviewerStrategy != "" {
+		if _, err := s.selectors.Get(*settings.ReviewerStrategy); err != nil {
+			return nil, err
+		}
+	}
+	if settings.MinApprovals != nil && *settings.MinApprovals < 0 {
+		return nil, ErrInvalidMinApprovals
+	}
+	if settings.OnReviewerDeactivation != nil && !validDeactivationBehavior(*settings.OnReviewerDeactivation) {
+		return nil, ErrUnknownDeactivationBehavior
+	}
+
+	var team *models.Team
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.teams.UpdateSettings(ctx, name, settings); err != nil {
+			return err
+		}
+		// sub-teams inherit the change, so their settings must hold up too
+		if err := s.checkSubtreeSettings(ctx, name); err != nil {
+			return err
+		}
+		var err error
+		team, err = s.teams.GetByName(ctx, name)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return team, nil
+}
+
+func (s *teamService) GetFallbacks(ctx context.Context, name string) ([]string, error) {
+	if _, err := s.teams.GetByName(ctx, name); err != nil {
+		return nil, err
+	}
+	return s.teams.ListFallbacks(ctx, name)
+}
+
+func (s *teamService) SetFallbacks(ctx context.Context, name string, fallbacks []string) ([]string, error) {
+	seen := make(map[string]bool, len(fallbacks))
+	for _, fb := range fallbacks {
+		if fb == name || seen[fb] {
+			return nil, ErrInvalidFallback
+		}
+		seen[fb] = true
+	}
+
+	var result []string
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if _, err := s.teams.GetByName(ctx, name); err != nil {
+			return err
+		}
+		for _, fb := range fallbacks {
+			if _, err := s.teams.GetByName(ctx, fb); err != nil {
+				return err
+			}
+		}
+		if err := s.teams.SetFallbacks(ctx, name, fallbacks); err != nil {
+			return err
+		}
+		var err error
+		result, err = s.teams.ListFallbacks(ctx, name)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
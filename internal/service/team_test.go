@@ -0,0 +1,152 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/service"
+)
+
+func TestSetMemberKeepsRoleAndLevel(t *testing.T) {
+	ts := newTestServices(t)
+	ctx := context.Background()
+
+	_, err := ts.team.AddTeam(ctx, "core", []models.TeamMember{
+		{UserID: "lead", Username: "lead", IsActive: true, Role: models.RoleLead, Level: models.LevelSenior},
+		{UserID: "dev", Username: "dev", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("AddTeam: %v", err)
+	}
+
+	for _, active := range []bool{false, true} {
+		m, err := ts.team.SetMember(ctx, service.SetMemberInput{TeamName: "core", UserID: "lead", IsActive: active})
+		if err != nil {
+			t.Fatalf("SetMember(is_active=%v): %v", active, err)
+		}
+		if m.IsActive != active || m.Role != models.RoleLead || m.Level != models.LevelSenior {
+			t.Errorf("SetMember(is_active=%v) = %+v, want an unchanged lead at senior level", active, m)
+		}
+	}
+
+	memberships, err := ts.users.ListMemberships(ctx, "lead")
+	if err != nil {
+		t.Fatalf("ListMemberships: %v", err)
+	}
+	if len(memberships) != 1 || memberships[0].Role != models.RoleLead || memberships[0].Level != models.LevelSenior {
+		t.Errorf("stored memberships = %+v", memberships)
+	}
+
+	// re-adding through AttachUser without role and level keeps them too
+	if err := ts.team.AttachUser(ctx, "core", strPtr("lead"), models.TeamMember{Username: "lead", IsActive: true}); err != nil {
+		t.Fatalf("AttachUser: %v", err)
+	}
+	memberships, err = ts.users.ListMemberships(ctx, "lead")
+	if err != nil {
+		t.Fatalf("ListMemberships: %v", err)
+	}
+	if memberships[0].Role != models.RoleLead || memberships[0].Level != models.LevelSenior {
+		t.Errorf("after AttachUser = %+v", memberships[0])
+	}
+
+	// a member added without role and level got the defaults
+	m, err := ts.team.SetMember(ctx, service.SetMemberInput{TeamName: "core", UserID: "dev", IsActive: true})
+	if err != nil {
+		t.Fatalf("SetMember: %v", err)
+	}
+	if m.Role != models.RoleMember || m.Level != models.LevelMiddle {
+		t.Errorf("SetMember = %+v, want member at middle level", m)
+	}
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestRemoveMemberHandsOffReviews(t *testing.T) {
+	ts := newTestServices(t)
+	ctx := context.Background()
+
+	_, err := ts.team.AddTeam(ctx, "core", []models.TeamMember{
+		{UserID: "a", Username: "a", IsActive: true},
+		{UserID: "b", Username: "b", IsActive: true},
+		{UserID: "c", Username: "c", IsActive: true},
+		{UserID: "d", Username: "d", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("AddTeam: %v", err)
+	}
+	pr, _, err := ts.pr.CreatePR(ctx, service.CreatePRInput{PullRequestID: "pr-1", Name: "pr", AuthorID: "a"})
+	if err != nil {
+		t.Fatalf("CreatePR: %v", err)
+	}
+	if len(pr.AssignedReviewers) != 2 {
+		t.Fatalf("reviewers = %v, want 2", pr.AssignedReviewers)
+	}
+	leaving := pr.AssignedReviewers[0]
+
+	if err := ts.team.RemoveMember(ctx, "core", leaving); err != nil {
+		t.Fatalf("RemoveMember: %v", err)
+	}
+
+	details, err := ts.pr.GetPR(ctx, "pr-1")
+	if err != nil {
+		t.Fatalf("GetPR: %v", err)
+	}
+	got := details.PR.AssignedReviewers
+	if len(got) != 2 || slices.Contains(got, leaving) {
+		t.Errorf("reviewers after removing %s = %v, want two others", leaving, got)
+	}
+	memberships, err := ts.users.ListMemberships(ctx, leaving)
+	if err != nil {
+		t.Fatalf("ListMemberships: %v", err)
+	}
+	if len(memberships) != 0 {
+		t.Errorf("memberships of %s = %+v, want none", leaving, memberships)
+	}
+}
+
+func TestUpdateSettingsChecksSubTeams(t *testing.T) {
+	ts := newTestServices(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"org", "core", "side"} {
+		if _, err := ts.team.CreateTeam(ctx, name, nil); err != nil {
+			t.Fatalf("CreateTeam(%s): %v", name, err)
+		}
+	}
+	if _, err := ts.team.SetParent(ctx, "core", strPtr("org")); err != nil {
+		t.Fatalf("SetParent: %v", err)
+	}
+	two, three := 2, 3
+	for _, name := range []string{"core", "side"} {
+		if _, err := ts.team.UpdateSettings(ctx, name, models.TeamSettings{MaxReviewers: &two}); err != nil {
+			t.Fatalf("UpdateSettings(%s): %v", name, err)
+		}
+	}
+
+	// core would inherit a default above its own maximum
+	_, err := ts.team.UpdateSettings(ctx, "org", models.TeamSettings{DefaultReviewers: &three})
+	if !errors.Is(err, service.ErrInvalidReviewerBounds) {
+		t.Fatalf("UpdateSettings(org) = %v, want ErrInvalidReviewerBounds", err)
+	}
+	org, err := ts.team.GetTeam(ctx, "org")
+	if err != nil {
+		t.Fatalf("GetTeam: %v", err)
+	}
+	if org.DefaultReviewers != nil {
+		t.Errorf("org default_reviewers = %d, want the rejected change rolled back", *org.DefaultReviewers)
+	}
+
+	// the same holds for a team moved under org once org has the default
+	if _, err := ts.team.SetParent(ctx, "core", nil); err != nil {
+		t.Fatalf("SetParent(nil): %v", err)
+	}
+	if _, err := ts.team.UpdateSettings(ctx, "org", models.TeamSettings{DefaultReviewers: &three}); err != nil {
+		t.Fatalf("UpdateSettings(org) without sub-teams: %v", err)
+	}
+	if _, err := ts.team.SetParent(ctx, "side", strPtr("org")); !errors.Is(err, service.ErrInvalidReviewerBounds) {
+		t.Errorf("SetParent(side) = %v, want ErrInvalidReviewerBounds", err)
+	}
+}
@@ -2,36 +2,109 @@ package service
 
 import (
 	"context"
-	"errors"
+	"io"
+	"path/filepath"
+	"pr-reviewer/internal/apperr"
 	"pr-reviewer/internal/models"
 	"pr-reviewer/internal/repository"
+	"pr-reviewer/internal/store"
+	"slices"
+)
+
+var (
+	ErrInvalidCapacity    = apperr.New(apperr.CodeValidation, "max_open_reviews must not be negative")
+	ErrInvalidAbsenceKind = apperr.New(apperr.CodeValidation, "kind must be one of VACATION, SICK_LEAVE, OTHER")
+	ErrInvalidAbsence     = apperr.New(apperr.CodeValidation, "ends_at must be after starts_at")
+	ErrInvalidExclusion   = apperr.New(apperr.CodeValidation, "reviewer_id must be set and differ from the author")
+	ErrInvalidClear       = apperr.New(apperr.CodeValidation, "clear accepts max_open_reviews, calendar_path, timezone, work_start, work_end, each not set in the same request")
 )
 
 type UserService interface {
+	// CreateUser creates the user, as a member of teamName when it is set.
 	CreateUser(ctx context.Context, username string, displayName *string, teamName *string) (*models.User, error)
 	GetUser(ctx context.Context, id string) (*models.User, error)
+	ListMemberships(ctx context.Context, id string) ([]models.Membership, error)
 	ListUsers(ctx context.Context) ([]models.User, error)
-	UpdateUser(ctx context.Context, id string, displayName *string, isActive *bool, teamName *string) error
+	UpdateUser(ctx context.Context, id string, upd models.UserUpdate) error
 	DeleteUser(ctx context.Context, id string) error
+
+	ListAbsences(ctx context.Context, userID string) ([]models.Absence, error)
+	AddAbsence(ctx context.Context, a models.Absence) (*models.Absence, error)
+	UpdateAbsence(ctx context.Context, a models.Absence) (*models.Absence, error)
+	DeleteAbsence(ctx context.Context, userID string, absenceID int64) error
+	ImportCalendar(ctx context.Context, userID string, r io.Reader) (*CalendarImport, error)
+	SyncCalendar(ctx context.Context, userID string) (*CalendarImport, error)
+
+	// ListExclusions returns the conflict-of-interest exclusions the user is either side of.
+	ListExclusions(ctx context.Context, userID string) ([]models.Exclusion, error)
+	// AddExclusion forbids e.ReviewerID to review pull requests of e.AuthorID,
+	// and the other way round when e.Mutual is set.
+	AddExclusion(ctx context.Context, e models.Exclusion) (*models.Exclusion, error)
+	DeleteExclusion(ctx context.Context, userID string, exclusionID int64) error
 }
 
 type userService struct {
-	users repository.UserRepository
-	teams repository.TeamRepository
+	tx         store.Transactor
+	users      repository.UserRepository
+	teams      repository.TeamRepository
+	absences   repository.AbsenceRepository
+	exclusions repository.ExclusionRepository
+	prs        PRService
+	// calendarDir is the root of the calendar_path of users; empty disables file sync
+	calendarDir string
 }
 
-func NewUserService(u repository.UserRepository, t repository.TeamRepository) UserService {
-	return &userService{users: u, teams: t}
+func NewUserService(
+	tx store.Transactor,
+	u repository.UserRepository,
+	t repository.TeamRepository,
+	a repository.AbsenceRepository,
+	e repository.ExclusionRepository,
+	prs PRService,
+	calendarDir string,
+) UserService {
+	return &userService{tx: tx, users: u, teams: t, absences: a, exclusions: e, prs: prs, calendarDir: calendarDir}
 }
 
 func (s *userService) CreateUser(ctx context.Context, username string, displayName *string, teamName *string) (*models.User, error) {
-	// business rule: if teamName provided, must exist
-	if teamName != nil {
-		if _, err := s.teams.GetByName(ctx, *teamName); err != nil {
-			return nil, errors.New("team not found")
+	var user *models.User
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		// business rule: if teamName provided, must exist
+		if teamName != nil {
+			if _, err := s.teams.GetByName(ctx, *teamName); err != nil {
+				return err
+			}
+		}
+		var err error
+		user, err = s.users.Create(ctx, username, displayName)
+		if err != nil || teamName == nil {
+			return err
+		}
+		m := &models.Membership{
+			TeamName: *teamName,
+			UserID:   user.UserID,
+			IsActive: true,
+			Role:     models.RoleMember,
+			Level:    models.LevelMiddle,
+		}
+		if err := s.users.AddMembership(ctx, m); err != nil {
+			return err
 		}
+		user.Teams = []string{*teamName}
+		user.TeamName = teamName
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return s.users.Create(ctx, username, displayName, teamName)
+	return user, nil
+}
+
+func (s *userService) ListMemberships(ctx context.Context, id string) ([]models.Membership, error) {
+	if _, err := s.users.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.users.ListMemberships(ctx, id)
 }
 
 func (s *userService) GetUser(ctx context.Context, id string) (*models.User, error) {
@@ -42,15 +115,147 @@ func (s *userService) ListUsers(ctx context.Context) ([]models.User, error) {
 	return s.users.List(ctx)
 }
 
-func (s *userService) UpdateUser(ctx context.Context, id string, displayName *string, isActive *bool, teamName *string) error {
-	if teamName != nil {
-		if _, err := s.teams.GetByName(ctx, *teamName); err != nil {
-			return errors.New("team not found")
+func (s *userService) UpdateUser(ctx context.Context, id string, upd models.UserUpdate) error {
+	if err := checkClear(upd); err != nil {
+		return err
+	}
+	if upd.MaxOpenReviews != nil && *upd.MaxOpenReviews < 0 {
+		return ErrInvalidCapacity
+	}
+	if upd.CalendarPath != nil && !filepath.IsLocal(*upd.CalendarPath) {
+		return ErrInvalidCalendarPath
+	}
+	if err := validateWorkHours(upd); err != nil {
+		return err
+	}
+	if upd.Skills != nil {
+		skills, err := normalizeSkills(upd.Skills)
+		if err != nil {
+			return err
 		}
+		upd.Skills = skills
 	}
-	return s.users.Update(ctx, id, displayName, isActive, teamName)
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		user, err := s.users.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.users.Update(ctx, id, upd); err != nil {
+			return err
+		}
+		if upd.Skills != nil {
+			if err := s.users.SetSkills(ctx, id, upd.Skills); err != nil {
+				return err
+			}
+		}
+		// an inactive reviewer would block their open pull requests
+		if user.IsActive && upd.IsActive != nil && !*upd.IsActive {
+			_, err = s.prs.HandOffReviews(ctx, []string{id}, "")
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// a raised capacity or a reactivated user can take queued reviews
+	if upd.MaxOpenReviews != nil || upd.IsActive != nil || slices.Contains(upd.Clear, "max_open_reviews") {
+		s.prs.DrainReviewQueue(ctx)
+	}
+	return nil
 }
 
 func (s *userService) DeleteUser(ctx context.Context, id string) error {
 	return s.users.Delete(ctx, id)
 }
+
+func (s *userService) ListAbsences(ctx context.Context, userID string) ([]models.Absence, error) {
+	if _, err := s.users.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.absences.ListByUser(ctx, userID)
+}
+
+func validateAbsence(a *models.Absence) error {
+	if a.Kind == "" {
+		a.Kind = models.AbsenceVacation
+	}
+	if !a.Kind.Valid() {
+		return ErrInvalidAbsenceKind
+	}
+	if !a.EndsAt.After(a.StartsAt) {
+		return ErrInvalidAbsence
+	}
+	return nil
+}
+
+func (s *userService) AddAbsence(ctx context.Context, a models.Absence) (*models.Absence, error) {
+	if err := validateAbsence(&a); err != nil {
+		return nil, err
+	}
+	if _, err := s.users.GetByID(ctx, a.UserID); err != nil {
+		return nil, err
+	}
+	if err := s.absences.Create(ctx, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (s *userService) UpdateAbsence(ctx context.Context, a models.Absence) (*models.Absence, error) {
+	if err := validateAbsence(&a); err != nil {
+		return nil, err
+	}
+	if err := s.absences.Update(ctx, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (s *userService) DeleteAbsence(ctx context.Context, userID string, absenceID int64) error {
+	return s.absences.Delete(ctx, userID, absenceID)
+}
+
+func (s *userService) ListExclusions(ctx context.Context, userID string) ([]models.Exclusion, error) {
+	if _, err := s.users.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.exclusions.ListByUser(ctx, userID)
+}
+
+func (s *userService) AddExclusion(ctx context.Context, e models.Exclusion) (*models.Exclusion, error) {
+	if e.ReviewerID == "" || e.ReviewerID == e.AuthorID {
+		return nil, ErrInvalidExclusion
+	}
+	for _, id := range []string{e.AuthorID, e.ReviewerID} {
+		if _, err := s.users.GetByID(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.exclusions.Create(ctx, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (s *userService) DeleteExclusion(ctx context.Context, userID string, exclusionID int64) error {
+	return s.exclusions.Delete(ctx, userID, exclusionID)
+}
+
+// checkClear rejects unknown fields in upd.Clear and fields the update sets
+// at the same time.
+func checkClear(upd models.UserUpdate) error {
+	set := map[string]bool{
+		"max_open_reviews": upd.MaxOpenReviews != nil,
+		"calendar_path":    upd.CalendarPath != nil,
+		"timezone":         upd.Timezone != nil,
+		"work_start":       upd.WorkStart != nil,
+		"work_end":         upd.WorkEnd != nil,
+	}
+	for _, field := range upd.Clear {
+		if isSet, ok := set[field]; !ok || isSet {
+			return ErrInvalidClear
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"pr-reviewer/internal/apperr"
+	"pr-reviewer/internal/models"
+)
+
+func TestUpdateUserClearsFields(t *testing.T) {
+	ts := newTestServices(t)
+	ctx := context.Background()
+
+	_, err := ts.team.AddTeam(ctx, "core", []models.TeamMember{{UserID: "a", Username: "a", IsActive: true}})
+	if err != nil {
+		t.Fatalf("AddTeam: %v", err)
+	}
+	limit := 2
+	err = ts.user.UpdateUser(ctx, "a", models.UserUpdate{
+		MaxOpenReviews: &limit,
+		CalendarPath:   strPtr("a.ics"),
+		Timezone:       strPtr("Europe/Moscow"),
+		WorkStart:      strPtr("09:00"),
+		WorkEnd:        strPtr("18:00"),
+	})
+	if err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+
+	// fields missing from the update are kept
+	if err := ts.user.UpdateUser(ctx, "a", models.UserUpdate{Clear: []string{"max_open_reviews", "work_start", "work_end"}}); err != nil {
+		t.Fatalf("UpdateUser(clear): %v", err)
+	}
+	u, err := ts.user.GetUser(ctx, "a")
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if u.MaxOpenReviews != nil || u.WorkStart != nil || u.WorkEnd != nil {
+		t.Errorf("cleared fields = %v, %v, %v, want nil", u.MaxOpenReviews, u.WorkStart, u.WorkEnd)
+	}
+	if u.CalendarPath == nil || *u.CalendarPath != "a.ics" || u.Timezone == nil || *u.Timezone != "Europe/Moscow" {
+		t.Errorf("kept fields = %v, %v", u.CalendarPath, u.Timezone)
+	}
+
+	for _, upd := range []models.UserUpdate{
+		{Clear: []string{"display_name"}},
+		{Timezone: strPtr("UTC"), Clear: []string{"timezone"}},
+	} {
+		if err := ts.user.UpdateUser(ctx, "a", upd); apperr.CodeOf(err) != apperr.CodeValidation {
+			t.Errorf("UpdateUser(%+v) = %v, want a validation error", upd, err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"sort"
+	"time"
+
+	"pr-reviewer/internal/apperr"
+	"pr-reviewer/internal/models"
+)
+
+var (
+	ErrInvalidTimezone  = apperr.New(apperr.CodeValidation, "timezone must be an IANA zone name such as Europe/Moscow")
+	ErrInvalidWorkHours = apperr.New(apperr.CodeValidation, "work_start and work_end must be HH:MM")
+)
+
+const clockLayout = "15:04"
+
+func validateWorkHours(upd models.UserUpdate) error {
+	if upd.Timezone != nil {
+		if _, err := time.LoadLocation(*upd.Timezone); err != nil || *upd.Timezone == "" {
+			return ErrInvalidTimezone
+		}
+	}
+	for _, v := range []*string{upd.WorkStart, upd.WorkEnd} {
+		if v == nil {
+			continue
+		}
+		if _, err := time.Parse(clockLayout, *v); err != nil {
+			return ErrInvalidWorkHours
+		}
+	}
+	return nil
+}
+
+// shiftWait returns how long until the user's working hours start, zero while
+// inside them. Users without working hours are always available. Windows
+// ending before they start span midnight.
+func shiftWait(u models.User, now time.Time) time.Duration {
+	if u.WorkStart == nil || u.WorkEnd == nil {
+		return 0
+	}
+	start, err1 := time.Parse(clockLayout, *u.WorkStart)
+	end, err2 := time.Parse(clockLayout, *u.WorkEnd)
+	if err1 != nil || err2 != nil || start.Equal(end) {
+		return 0
+	}
+	local := now.In(userLocation(u))
+	minute := func(t time.Time) int { return t.Hour()*60 + t.Minute() }
+	cur, from, to := minute(local), minute(start), minute(end)
+
+	inside := from <= cur && cur < to
+	if from > to {
+		inside = cur >= from || cur < to
+	}
+	if inside {
+		return 0
+	}
+	wait := from - cur
+	if wait < 0 {
+		wait += 24 * 60
+	}
+	return time.Duration(wait)*time.Minute - time.Duration(local.Second())*time.Second
+}
+
+// splitByShift separates candidates inside their working hours from the rest,
+// which are ordered by who starts soonest. Both keep the input order otherwise.
+func splitByShift(candidates []models.Candidate, now time.Time) (onShift, later []models.Candidate) {
+	waits := make(map[string]time.Duration)
+	for _, c := range candidates {
+		if w := shiftWait(c.User, now); w > 0 {
+			waits[c.UserID] = w
+			later = append(later, c)
+			continue
+		}
+		onShift = append(onShift, c)
+	}
+	sort.SliceStable(later, func(i, j int) bool { return waits[later[i].UserID] < waits[later[j].UserID] })
+	return onShift, later
+}
+
+// userLocation returns the user's time zone, UTC when unset or unknown.
+func userLocation(u models.User) *time.Location {
+	if u.Timezone != nil {
+		if loc, err := time.LoadLocation(*u.Timezone); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"pr-reviewer/internal/models"
+)
+
+func TestValidateWorkHours(t *testing.T) {
+	tests := []struct {
+		name string
+		upd  models.UserUpdate
+		want error
+	}{
+		{name: "nothing set"},
+		{name: "zone and hours", upd: models.UserUpdate{Timezone: strPtr("Europe/Moscow"), WorkStart: strPtr("09:00"), WorkEnd: strPtr("18:30")}},
+		{name: "UTC", upd: models.UserUpdate{Timezone: strPtr("UTC")}},
+		{name: "empty zone", upd: models.UserUpdate{Timezone: strPtr("")}, want: ErrInvalidTimezone},
+		{name: "unknown zone", upd: models.UserUpdate{Timezone: strPtr("Mars/Olympus")}, want: ErrInvalidTimezone},
+		{name: "start without minutes", upd: models.UserUpdate{WorkStart: strPtr("9")}, want: ErrInvalidWorkHours},
+		{name: "end past midnight", upd: models.UserUpdate{WorkEnd: strPtr("24:00")}, want: ErrInvalidWorkHours},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateWorkHours(tt.upd); !errors.Is(err, tt.want) {
+				t.Errorf("validateWorkHours = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// shift returns a user working between start and end in the zone.
+func shift(start, end, zone string) models.User {
+	u := models.User{WorkStart: &start, WorkEnd: &end}
+	if zone != "" {
+		u.Timezone = &zone
+	}
+	return u
+}
+
+func TestShiftWait(t *testing.T) {
+	at := func(clock string) time.Time {
+		now, err := time.Parse(time.DateTime, "2026-10-18 "+clock)
+		if err != nil {
+			t.Fatalf("parse %s: %v", clock, err)
+		}
+		return now
+	}
+	tests := []struct {
+		name string
+		user models.User
+		now  string
+		want time.Duration
+	}{
+		{name: "no working hours", user: models.User{}, now: "03:00:00"},
+		{name: "only a start", user: models.User{WorkStart: strPtr("09:00")}, now: "03:00:00"},
+		{name: "inside", user: shift("09:00", "18:00", ""), now: "10:30:00"},
+		{name: "at the start", user: shift("09:00", "18:00", ""), now: "09:00:00"},
+		{name: "at the end", user: shift("09:00", "18:00", ""), now: "18:00:00", want: 15 * time.Hour},
+		{name: "before", user: shift("11:00", "18:00", ""), now: "10:30:20", want: 29*time.Minute + 40*time.Second},
+		{name: "after", user: shift("09:00", "10:00", ""), now: "10:30:00", want: 22*time.Hour + 30*time.Minute},
+		{name: "night shift, evening", user: shift("22:00", "06:00", ""), now: "23:00:00"},
+		{name: "night shift, morning", user: shift("22:00", "06:00", ""), now: "03:00:00"},
+		{name: "night shift, day", user: shift("22:00", "06:00", ""), now: "10:30:00", want: 11*time.Hour + 30*time.Minute},
+		{name: "local time", user: shift("14:00", "18:00", "Europe/Moscow"), now: "10:30:00", want: 30 * time.Minute},
+		{name: "unknown zone is UTC", user: shift("14:00", "18:00", "Mars/Olympus"), now: "10:30:00", want: 3*time.Hour + 30*time.Minute},
+		{name: "empty window", user: shift("09:00", "09:00", ""), now: "03:00:00"},
+		{name: "broken clock", user: shift("9am", "18:00", ""), now: "03:00:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shiftWait(tt.user, at(tt.now)); got != tt.want {
+				t.Errorf("shiftWait = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitByShift(t *testing.T) {
+	now := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+	candidate := func(id string, u models.User) models.Candidate {
+		u.UserID = id
+		return models.Candidate{User: u}
+	}
+	onShift, later := splitByShift([]models.Candidate{
+		candidate("late", shift("14:00", "18:00", "")),
+		candidate("any", models.User{}),
+		candidate("soon", shift("11:00", "18:00", "")),
+		candidate("day", shift("09:00", "18:00", "")),
+		candidate("also-late", shift("14:00", "20:00", "")),
+	}, now)
+	if ids := candidateIDs(onShift); !slices.Equal(ids, []string{"any", "day"}) {
+		t.Errorf("on shift = %v, want [any day]", ids)
+	}
+	if ids := candidateIDs(later); !slices.Equal(ids, []string{"soon", "late", "also-late"}) {
+		t.Errorf("later = %v, want [soon late also-late]", ids)
+	}
+}
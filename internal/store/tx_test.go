@@ -0,0 +1,58 @@
+package store_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"pr-reviewer/internal/store"
+	"pr-reviewer/internal/store/storetest"
+)
+
+func TestWithinTxRetriesDeadlocks(t *testing.T) {
+	s := storetest.New(t)
+	ctx := context.Background()
+	pool := s.Pool()
+	if _, err := pool.Exec(ctx, `CREATE TABLE locks (id INT PRIMARY KEY)`); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+	if _, err := pool.Exec(ctx, `INSERT INTO locks VALUES (1), (2)`); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	lock := func(ctx context.Context, id int) error {
+		_, err := store.Conn(ctx, pool).Exec(ctx, `SELECT 1 FROM locks WHERE id = $1 FOR UPDATE`, id)
+		return err
+	}
+
+	// both first attempts hold one row before asking for the other one
+	var holding sync.WaitGroup
+	holding.Add(2)
+	var calls atomic.Int32
+	run := func(first, second int) error {
+		var once sync.Once
+		return s.WithinTx(ctx, func(ctx context.Context) error {
+			calls.Add(1)
+			if err := lock(ctx, first); err != nil {
+				return err
+			}
+			once.Do(func() {
+				holding.Done()
+				holding.Wait()
+			})
+			return lock(ctx, second)
+		})
+	}
+
+	errs := make(chan error, 2)
+	go func() { errs <- run(1, 2) }()
+	go func() { errs <- run(2, 1) }()
+	for range 2 {
+		if err := <-errs; err != nil {
+			t.Fatalf("WithinTx: %v", err)
+		}
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("fn called %d times, want 3: the deadlock victim runs again", n)
+	}
+}
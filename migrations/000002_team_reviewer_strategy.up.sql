@@ -0,0 +1,3 @@
+-- 000002_team_reviewer_strategy.up.sql
+-- NULL means "use the service default strategy"
+ALTER TABLE teams ADD COLUMN reviewer_strategy TEXT;
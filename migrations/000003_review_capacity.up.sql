@@ -0,0 +1,10 @@
+-- 000003_review_capacity.up.sql
+-- NULL means "no limit"
+ALTER TABLE users ADD COLUMN max_open_reviews INT CHECK (max_open_reviews >= 0);
+
+-- reviewer slots that could not be filled because every candidate was at capacity
+CREATE TABLE pr_review_queue (
+                                 pull_request_id UUID PRIMARY KEY REFERENCES prs(pull_request_id) ON DELETE CASCADE,
+                                 missing_reviewers INT NOT NULL CHECK (missing_reviewers > 0),
+                                 queued_at TIMESTAMPTZ DEFAULT now()
+);
@@ -0,0 +1,17 @@
+-- 000004_api_contract.up.sql
+-- column names used by the code
+ALTER TABLE prs RENAME COLUMN "createdAt" TO created_at;
+ALTER TABLE prs RENAME COLUMN "mergedAt" TO merged_at;
+
+-- user ids are supplied by clients (e.g. "u1"), not only UUIDs
+ALTER TABLE pr_reviewers DROP CONSTRAINT pr_reviewers_reviewer_id_fkey;
+ALTER TABLE prs DROP CONSTRAINT prs_author_id_fkey;
+
+ALTER TABLE users ALTER COLUMN user_id DROP DEFAULT;
+ALTER TABLE users ALTER COLUMN user_id TYPE TEXT;
+ALTER TABLE users ALTER COLUMN user_id SET DEFAULT gen_random_uuid()::text;
+ALTER TABLE prs ALTER COLUMN author_id TYPE TEXT;
+ALTER TABLE pr_reviewers ALTER COLUMN reviewer_id TYPE TEXT;
+
+ALTER TABLE prs ADD CONSTRAINT prs_author_id_fkey FOREIGN KEY (author_id) REFERENCES users(user_id);
+ALTER TABLE pr_reviewers ADD CONSTRAINT pr_reviewers_reviewer_id_fkey FOREIGN KEY (reviewer_id) REFERENCES users(user_id);
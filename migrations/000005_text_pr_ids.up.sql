@@ -0,0 +1,15 @@
+-- 000005_text_pr_ids.up.sql
+-- pull request ids come from the VCS (e.g. "pr-1001")
+ALTER TABLE pr_reviewers DROP CONSTRAINT pr_reviewers_pull_request_id_fkey;
+ALTER TABLE pr_review_queue DROP CONSTRAINT pr_review_queue_pull_request_id_fkey;
+
+ALTER TABLE prs ALTER COLUMN pull_request_id DROP DEFAULT;
+ALTER TABLE prs ALTER COLUMN pull_request_id TYPE TEXT;
+ALTER TABLE prs ALTER COLUMN pull_request_id SET DEFAULT gen_random_uuid()::text;
+ALTER TABLE pr_reviewers ALTER COLUMN pull_request_id TYPE TEXT;
+ALTER TABLE pr_review_queue ALTER COLUMN pull_request_id TYPE TEXT;
+
+ALTER TABLE pr_reviewers ADD CONSTRAINT pr_reviewers_pull_request_id_fkey
+    FOREIGN KEY (pull_request_id) REFERENCES prs(pull_request_id) ON DELETE CASCADE;
+ALTER TABLE pr_review_queue ADD CONSTRAINT pr_review_queue_pull_request_id_fkey
+    FOREIGN KEY (pull_request_id) REFERENCES prs(pull_request_id) ON DELETE CASCADE;
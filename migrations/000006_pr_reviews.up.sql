@@ -0,0 +1,15 @@
+-- 000006_pr_reviews.up.sql
+CREATE TYPE review_verdict AS ENUM ('APPROVED', 'CHANGES_REQUESTED', 'COMMENTED');
+
+-- review history: every submitted verdict is kept, the latest one per reviewer counts
+CREATE TABLE pr_reviews (
+                            review_id BIGSERIAL PRIMARY KEY,
+                            pull_request_id TEXT NOT NULL REFERENCES prs(pull_request_id) ON DELETE CASCADE,
+                            reviewer_id TEXT NOT NULL REFERENCES users(user_id),
+                            verdict review_verdict NOT NULL,
+                            message TEXT,
+                            created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX pr_reviews_pull_request_idx ON pr_reviews (pull_request_id, created_at);
+CREATE INDEX pr_reviews_reviewer_idx ON pr_reviews (reviewer_id, created_at);
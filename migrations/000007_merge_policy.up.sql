@@ -0,0 +1,19 @@
+-- 000007_merge_policy.up.sql
+-- NULL means "use the service default"
+ALTER TABLE teams ADD COLUMN min_approvals INT CHECK (min_approvals >= 0);
+ALTER TABLE teams ADD COLUMN block_on_changes_requested BOOLEAN;
+ALTER TABLE teams ADD COLUMN forbid_self_approval BOOLEAN;
+
+ALTER TABLE users ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT false;
+
+-- PR history: merges, forced merges and other notable changes
+CREATE TABLE pr_events (
+                           event_id BIGSERIAL PRIMARY KEY,
+                           pull_request_id TEXT NOT NULL REFERENCES prs(pull_request_id) ON DELETE CASCADE,
+                           event_type TEXT NOT NULL,
+                           actor_id TEXT REFERENCES users(user_id),
+                           details JSONB,
+                           created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX pr_events_pull_request_idx ON pr_events (pull_request_id, created_at);
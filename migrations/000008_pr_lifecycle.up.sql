@@ -0,0 +1,6 @@
+-- 000008_pr_lifecycle.up.sql
+-- DRAFT: no reviewers until marked ready; CLOSED: abandoned without merge
+ALTER TYPE pr_status ADD VALUE IF NOT EXISTS 'DRAFT';
+ALTER TYPE pr_status ADD VALUE IF NOT EXISTS 'CLOSED';
+
+ALTER TABLE prs ADD COLUMN closed_at TIMESTAMPTZ;
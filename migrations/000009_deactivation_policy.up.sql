@@ -0,0 +1,4 @@
+-- 000009_deactivation_policy.up.sql
+-- what happens to open reviews of a member who becomes inactive; NULL means 'reassign'
+ALTER TABLE teams ADD COLUMN on_reviewer_deactivation TEXT
+    CHECK (on_reviewer_deactivation IN ('reassign', 'leave', 'flag'));
@@ -0,0 +1,14 @@
+-- 000010_user_absences.up.sql
+-- periods in which a user is not picked as a reviewer, even though is_active stays true
+CREATE TABLE user_absences (
+                               absence_id BIGSERIAL PRIMARY KEY,
+                               user_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+                               kind TEXT NOT NULL CHECK (kind IN ('VACATION', 'SICK_LEAVE', 'OTHER')),
+                               starts_at TIMESTAMPTZ NOT NULL,
+                               ends_at TIMESTAMPTZ NOT NULL,
+                               note TEXT,
+                               created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                               CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX user_absences_user_idx ON user_absences (user_id, ends_at);
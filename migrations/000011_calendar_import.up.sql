@@ -0,0 +1,7 @@
+-- 000011_calendar_import.up.sql
+-- ICS file the user's absences are synced from, relative to CALENDAR_DIR
+ALTER TABLE users ADD COLUMN calendar_path TEXT;
+
+-- 'manual' absences are managed through the API, 'ics' ones are replaced on every import
+ALTER TABLE user_absences ADD COLUMN source TEXT NOT NULL DEFAULT 'manual' CHECK (source IN ('manual', 'ics'));
+ALTER TABLE user_absences ADD COLUMN external_uid TEXT;
@@ -0,0 +1,5 @@
+-- 000012_working_hours.up.sql
+-- IANA timezone and local working hours ("HH:MM"); NULL means always available
+ALTER TABLE users ADD COLUMN timezone TEXT;
+ALTER TABLE users ADD COLUMN work_start TEXT CHECK (work_start ~ '^([01][0-9]|2[0-3]):[0-5][0-9]$');
+ALTER TABLE users ADD COLUMN work_end TEXT CHECK (work_end ~ '^([01][0-9]|2[0-3]):[0-5][0-9]$');
@@ -0,0 +1,8 @@
+-- 000013_reviewer_count.up.sql
+-- team bounds for the number of reviewers per PR; NULL means the service default
+ALTER TABLE teams ADD COLUMN default_reviewers INT CHECK (default_reviewers >= 0);
+ALTER TABLE teams ADD COLUMN min_reviewers INT CHECK (min_reviewers >= 0);
+ALTER TABLE teams ADD COLUMN max_reviewers INT CHECK (max_reviewers >= 0);
+
+-- number of reviewers the PR should have
+ALTER TABLE prs ADD COLUMN reviewer_count INT NOT NULL DEFAULT 2 CHECK (reviewer_count >= 0);
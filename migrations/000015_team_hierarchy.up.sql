@@ -0,0 +1,6 @@
+-- 000015_team_hierarchy.up.sql
+-- sub-teams inherit unset settings from their parent and escalate reviewer search to it
+ALTER TABLE teams ADD COLUMN parent_team TEXT REFERENCES teams(team_name) ON DELETE SET NULL
+    CHECK (parent_team <> team_name);
+
+CREATE INDEX teams_parent_team_idx ON teams (parent_team);
@@ -0,0 +1,18 @@
+-- 000016_team_members.up.sql
+-- a user can belong to several teams; is_active pauses the membership without
+-- touching the user, role distinguishes team leads from regular members
+CREATE TABLE team_members (
+                              team_name TEXT NOT NULL REFERENCES teams(team_name) ON DELETE RESTRICT,
+                              user_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+                              is_active BOOLEAN NOT NULL DEFAULT true,
+                              role TEXT NOT NULL DEFAULT 'member' CHECK (role IN ('member', 'lead')),
+                              joined_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                              PRIMARY KEY (team_name, user_id)
+);
+
+CREATE INDEX team_members_user_idx ON team_members (user_id);
+
+INSERT INTO team_members (team_name, user_id)
+SELECT team_name, user_id FROM users WHERE team_name IS NOT NULL;
+
+ALTER TABLE users DROP COLUMN team_name;
@@ -0,0 +1,14 @@
+-- 000017_codeowners.up.sql
+-- CODEOWNERS file of a team, kept as uploaded
+CREATE TABLE team_codeowners (
+                                 team_name TEXT PRIMARY KEY REFERENCES teams(team_name) ON DELETE CASCADE,
+                                 content TEXT NOT NULL,
+                                 updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- paths changed by a pull request, matched against the CODEOWNERS rules
+CREATE TABLE pr_files (
+                          pull_request_id TEXT NOT NULL REFERENCES prs(pull_request_id) ON DELETE CASCADE,
+                          path TEXT NOT NULL,
+                          PRIMARY KEY (pull_request_id, path)
+);
@@ -0,0 +1,16 @@
+-- 000018_skills.up.sql
+-- areas of expertise of a user, e.g. postgres, frontend, security
+CREATE TABLE user_skills (
+                             user_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+                             skill TEXT NOT NULL,
+                             PRIMARY KEY (user_id, skill)
+);
+
+CREATE INDEX user_skills_skill_idx ON user_skills (skill);
+
+-- areas a pull request needs a reviewer for
+CREATE TABLE pr_skills (
+                           pull_request_id TEXT NOT NULL REFERENCES prs(pull_request_id) ON DELETE CASCADE,
+                           skill TEXT NOT NULL,
+                           PRIMARY KEY (pull_request_id, skill)
+);
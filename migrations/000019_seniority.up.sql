@@ -0,0 +1,10 @@
+-- 000019_seniority.up.sql
+-- seniority of a member within the team
+ALTER TABLE team_members
+    ADD COLUMN level TEXT NOT NULL DEFAULT 'middle' CHECK (level IN ('junior', 'middle', 'senior', 'lead'));
+
+-- reviewers at senior level or above every PR needs, and whether PRs of
+-- juniors need a lead among their reviewers
+ALTER TABLE teams
+    ADD COLUMN min_senior_reviewers INT CHECK (min_senior_reviewers >= 0),
+    ADD COLUMN junior_needs_lead BOOLEAN;
@@ -0,0 +1,15 @@
+-- 000020_review_exclusions.up.sql
+-- reviewer_id never reviews pull requests of author_id; mutual rows forbid
+-- the opposite direction as well
+CREATE TABLE review_exclusions (
+                                   exclusion_id BIGSERIAL PRIMARY KEY,
+                                   author_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+                                   reviewer_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+                                   mutual BOOLEAN NOT NULL DEFAULT false,
+                                   reason TEXT,
+                                   created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                                   UNIQUE (author_id, reviewer_id),
+                                   CHECK (author_id <> reviewer_id)
+);
+
+CREATE INDEX review_exclusions_reviewer_idx ON review_exclusions (reviewer_id);
@@ -0,0 +1,4 @@
+-- 000021_round_robin_cursor.up.sql
+-- user_id of the last reviewer the round_robin strategy picked in the team,
+-- so the rotation survives restarts and is shared by all replicas
+ALTER TABLE teams ADD COLUMN last_assigned TEXT;
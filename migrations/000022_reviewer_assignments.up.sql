@@ -0,0 +1,17 @@
+-- 000022_reviewer_assignments.up.sql
+-- append-only log of reviewer assignments for the pairing history; pr_reviewers
+-- only keeps the current reviewers and loses replaced or removed ones
+CREATE TABLE reviewer_assignments (
+                                      assignment_id BIGSERIAL PRIMARY KEY,
+                                      pull_request_id TEXT NOT NULL REFERENCES prs(pull_request_id) ON DELETE CASCADE,
+                                      reviewer_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+                                      assigned_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX reviewer_assignments_pull_request_idx ON reviewer_assignments (pull_request_id);
+CREATE INDEX reviewer_assignments_reviewer_idx ON reviewer_assignments (reviewer_id, assigned_at);
+
+INSERT INTO reviewer_assignments (pull_request_id, reviewer_id, assigned_at)
+SELECT r.pull_request_id, r.reviewer_id, COALESCE(r.assigned_at, p.created_at)
+FROM pr_reviewers r
+JOIN prs p ON p.pull_request_id = r.pull_request_id;
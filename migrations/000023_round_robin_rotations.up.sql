@@ -0,0 +1,16 @@
+-- 000023_round_robin_rotations.up.sql
+-- round robin cursors per team and rotation, so that picking among the code
+-- owners does not move the cursor of the rotation over all members
+CREATE TABLE round_robin_cursors (
+                                     team_name TEXT NOT NULL REFERENCES teams(team_name) ON DELETE CASCADE,
+                                     rotation TEXT NOT NULL,
+                                     last_assigned TEXT,
+                                     PRIMARY KEY (team_name, rotation)
+);
+
+INSERT INTO round_robin_cursors (team_name, rotation, last_assigned)
+SELECT team_name, 'team', last_assigned
+FROM teams
+WHERE last_assigned IS NOT NULL;
+
+ALTER TABLE teams DROP COLUMN last_assigned;